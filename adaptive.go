@@ -0,0 +1,94 @@
+package hashfs
+
+import (
+	"io/fs"
+	"path"
+	"sort"
+)
+
+// WithMinHashLength truncates the encoded digests to the first n
+// characters like WithHashLength, except that WarmCache lengthens the
+// digests of files in the same directory as needed so that no two of
+// them share a digest prefix. Open accepts any such lengthened digest.
+// Ignored with a custom Hasher. Panics if n is not positive or exceeds
+// the encoded digest length.
+func WithMinHashLength(n int) Option {
+	if n <= 0 {
+		panic("hashfs: hash length must be positive")
+	}
+	return func(f *FS) {
+		f.length = n
+		f.adaptive = true
+	}
+}
+
+// matchLonger reports whether want is a digest of the given raw digest
+// lengthened beyond the minimum length.
+func (f *FS) matchLonger(want string, sum []byte) bool {
+	if !f.adaptive || sum == nil || len(want) <= f.length {
+		return false
+	}
+	full := f.enc.encode(sum)
+	return len(want) <= len(full) && f.equal(want, full[:len(want)])
+}
+
+// disambiguate lengthens the truncated digests of the files hashed so
+// far that share a digest prefix with another file in their directory.
+// Returns ErrCollision if a lengthened hashed file name already resolves
+// to another file.
+func (f *FS) disambiguate() error {
+	if !f.adaptive || f.impl != nil {
+		return nil
+	}
+	dirs := make(map[string]map[string][]string) // ["dir"]["full"] => ["base.ext"]
+	f.mu.RLock()
+	for name := range f.hash {
+		sum, ok := f.sum[name]
+		if !ok {
+			continue
+		}
+		dir := path.Dir(name)
+		if dirs[dir] == nil {
+			dirs[dir] = make(map[string][]string)
+		}
+		full := f.enc.encode(sum)
+		dirs[dir][full] = append(dirs[dir][full], name)
+	}
+	f.mu.RUnlock()
+	for _, files := range dirs {
+		digests := make([]string, 0, len(files))
+		for full := range files {
+			digests = append(digests, full)
+		}
+		sort.Strings(digests)
+		for i, full := range digests {
+			n := f.length
+			if i > 0 && commonPrefix(full, digests[i-1]) >= n {
+				n = commonPrefix(full, digests[i-1]) + 1
+			}
+			if i < len(digests)-1 && commonPrefix(full, digests[i+1]) >= n {
+				n = commonPrefix(full, digests[i+1]) + 1
+			}
+			for _, name := range files[full] {
+				hash, _ := f.getHash(name)
+				if hash == full[:n] {
+					continue
+				}
+				err := f.setHash(name, full[:n], nil)
+				if err != nil {
+					return &fs.PathError{Op: "hash", Path: name, Err: err}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// commonPrefix returns the length of the common prefix of a and b.
+func commonPrefix(a, b string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
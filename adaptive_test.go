@@ -0,0 +1,87 @@
+package hashfs
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestWithMinHashLength(t *testing.T) {
+	m := fstest.MapFS{
+		"a.txt":       &fstest.MapFile{Data: []byte("1")},
+		"b.txt":       &fstest.MapFile{Data: []byte("12")},
+		"other/c.txt": &fstest.MapFile{Data: []byte("12")},
+	}
+	h := New(m, WithMinHashLength(2))
+	if h.Name("a.txt") != "a.6b.txt" {
+		t.Errorf("Name\nhave '%s'\nwant '%s'", h.Name("a.txt"), "a.6b.txt")
+	}
+	err := h.WarmCache()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"a.txt", "a.6b8.txt"},
+		{"b.txt", "b.6b5.txt"},
+		{"other/c.txt", "other/c.6b.txt"},
+	}
+	for _, tt := range tests {
+		have := h.Name(tt.name)
+		if have != tt.want {
+			t.Errorf("Name(%q)\nhave '%s'\nwant '%s'", tt.name, have, tt.want)
+		}
+		g := New(m, WithMinHashLength(2))
+		base, ok := g.Base(tt.want)
+		if !ok || base != tt.name {
+			t.Errorf("Base(%q)\nhave '%s'\nwant '%s'", tt.want, base, tt.name)
+		}
+		want := New(m, WithMinHashLength(2)).Name(tt.name)
+		if g.Name(tt.name) != want {
+			t.Errorf("Name after Base(%q)\nhave '%s'\nwant '%s'", tt.want, g.Name(tt.name), want)
+		}
+	}
+	const full = "a.6b86b273ff34fce19d6b804eff5a3f5747ada4eaa22f1d49c01e52ddb7875b4b.txt"
+	f, err := h.Open(full)
+	if err != nil {
+		t.Fatalf("Open should serve longer digests, have %v", err)
+	}
+	f.Close()
+	if h.Name("a.txt") != "a.6b8.txt" {
+		t.Errorf("Name after Open(%q)\nhave '%s'\nwant '%s'", full, h.Name("a.txt"), "a.6b8.txt")
+	}
+	_, ok := New(m, WithMinHashLength(2)).Base("a.6b5.txt")
+	if ok {
+		t.Errorf("Base should not resolve mismatched digests")
+	}
+	f, err = h.Open("a.6b.txt")
+	if err != nil {
+		t.Fatalf("Open should serve shorter digests, have %v", err)
+	}
+	f.Close()
+	if h.Name("a.txt") != "a.6b8.txt" {
+		t.Errorf("Name after Open\nhave '%s'\nwant '%s'", h.Name("a.txt"), "a.6b8.txt")
+	}
+}
+
+func TestCollision(t *testing.T) {
+	// The digests of "9", "19" and "50" start with "1958", "9400" and "1a65".
+	m := fstest.MapFS{
+		"a.txt":  &fstest.MapFile{Data: []byte("9")},
+		"a1.txt": &fstest.MapFile{Data: []byte("19")},
+		"b.txt":  &fstest.MapFile{Data: []byte("50")},
+	}
+	h := New(m, WithNameTemplate("{base}{hash}{ext}"), WithMinHashLength(1))
+	err := h.WarmCache()
+	if !errors.Is(err, ErrCollision) {
+		t.Errorf("WarmCache should yield ErrCollision, have %v", err)
+	}
+	g := New(m)
+	err = g.LoadManifest(strings.NewReader(`{"a.c":"b","a":"b.c"}`))
+	if !errors.Is(err, ErrCollision) {
+		t.Errorf("LoadManifest should yield ErrCollision, have %v", err)
+	}
+}
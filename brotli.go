@@ -0,0 +1,26 @@
+//go:build brotli
+// +build brotli
+
+package hashfs
+
+import (
+	"io"
+
+	"github.com/andybalholm/brotli"
+)
+
+// WithBrotli compresses text assets with brotli at the given quality
+// as they are hashed by WarmCache. FileServer prefers the brotli
+// variant over gzip when the client accepts both. Requires the
+// brotli build tag. Panics if quality is not a valid brotli quality.
+func WithBrotli(quality int) Option {
+	if quality < brotli.BestSpeed || quality > brotli.BestCompression {
+		panic("hashfs: invalid brotli quality")
+	}
+	return withCompressor(compressor{
+		encoding: "br",
+		compress: func(w io.Writer) (io.WriteCloser, error) {
+			return brotli.NewWriterLevel(w, quality), nil
+		},
+	})
+}
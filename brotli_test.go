@@ -0,0 +1,55 @@
+//go:build brotli
+// +build brotli
+
+package hashfs
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestBrotli(t *testing.T) {
+	m := fstest.MapFS{"app.js": &fstest.MapFile{Data: []byte("console.log('hello');")}}
+	tests := []struct {
+		opts   []Option
+		header string
+		want   string
+	}{
+		{[]Option{WithPrecompress(gzip.BestCompression), WithBrotli(brotli.BestCompression)}, "gzip, br", "br"},
+		{[]Option{WithBrotli(brotli.BestCompression), WithPrecompress(gzip.BestCompression)}, "gzip, br", "br"},
+		{[]Option{WithBrotli(brotli.BestCompression), WithPrecompress(gzip.BestCompression)}, "gzip", "gzip"},
+		{[]Option{WithBrotli(brotli.BestCompression)}, "gzip", ""},
+	}
+	for i, tt := range tests {
+		h := New(m, tt.opts...)
+		err := h.WarmCache()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		r := httptest.NewRequest(http.MethodGet, "/"+h.Name("app.js"), nil)
+		r.Header.Set("Accept-Encoding", tt.header)
+		w := httptest.NewRecorder()
+		h.FileServer().ServeHTTP(w, r)
+		encoding := w.Header().Get("Content-Encoding")
+		if encoding != tt.want {
+			t.Errorf("%d. Content-Encoding\nhave '%s'\nwant '%s'", i, encoding, tt.want)
+			continue
+		}
+		if encoding != "br" {
+			continue
+		}
+		b, err := io.ReadAll(brotli.NewReader(w.Body))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if string(b) != string(m["app.js"].Data) {
+			t.Errorf("%d. body\nhave %q\nwant %q", i, b, m["app.js"].Data)
+		}
+	}
+}
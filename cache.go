@@ -0,0 +1,59 @@
+package hashfs
+
+import (
+	"path"
+	"strings"
+)
+
+// Cache represents a store of digests that may be shared between several
+// FS instances, such as server processes serving the same files with the
+// same configuration. Implementations must be safe for concurrent use.
+type Cache interface {
+	// GetHash returns the encoded digest of the given file.
+	GetHash(name string) (string, bool)
+	// SetHash stores the encoded digest of the given file.
+	SetHash(name, hash string)
+	// GetBase returns the original file name of the given hashed file name.
+	GetBase(name string) (string, bool)
+	// SetBase stores the original file name of the given hashed file name.
+	SetBase(name, base string)
+}
+
+// WithCache consults the given cache for digests that are not in memory
+// before computing them, and stores computed digests in it. Invalidate
+// and Reset only affect the in-memory cache.
+func WithCache(cache Cache) Option {
+	return func(f *FS) {
+		f.cache = cache
+	}
+}
+
+// subCache is a Cache for the files under dir.
+type subCache struct {
+	dir   string
+	cache Cache
+}
+
+// GetHash implements the Cache interface.
+func (c subCache) GetHash(name string) (string, bool) {
+	return c.cache.GetHash(path.Join(c.dir, name))
+}
+
+// SetHash implements the Cache interface.
+func (c subCache) SetHash(name, hash string) {
+	c.cache.SetHash(path.Join(c.dir, name), hash)
+}
+
+// GetBase implements the Cache interface.
+func (c subCache) GetBase(name string) (string, bool) {
+	base, ok := c.cache.GetBase(path.Join(c.dir, name))
+	if !ok || !strings.HasPrefix(base, c.dir+"/") {
+		return "", false
+	}
+	return strings.TrimPrefix(base, c.dir+"/"), true
+}
+
+// SetBase implements the Cache interface.
+func (c subCache) SetBase(name, base string) {
+	c.cache.SetBase(path.Join(c.dir, name), path.Join(c.dir, base))
+}
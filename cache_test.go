@@ -0,0 +1,85 @@
+package hashfs
+
+import (
+	"io/fs"
+	"sync"
+	"testing"
+	"testing/fstest"
+)
+
+type testCache struct {
+	mu   sync.Mutex
+	hash map[string]string
+	base map[string]string
+}
+
+func newTestCache() *testCache {
+	return &testCache{hash: make(map[string]string), base: make(map[string]string)}
+}
+
+func (c *testCache) GetHash(name string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	hash, ok := c.hash[name]
+	return hash, ok
+}
+
+func (c *testCache) SetHash(name, hash string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.hash[name] = hash
+}
+
+func (c *testCache) GetBase(name string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	base, ok := c.base[name]
+	return base, ok
+}
+
+func (c *testCache) SetBase(name, base string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.base[name] = base
+}
+
+func TestWithCache(t *testing.T) {
+	m := fstest.MapFS{
+		"dir/a.txt": &fstest.MapFile{Data: []byte("a")},
+	}
+	cache := newTestCache()
+	const name = "dir/a.ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb.txt"
+	h := New(m, WithCache(cache))
+	if h.Name("dir/a.txt") != name {
+		t.Fatalf("Name\nhave '%s'\nwant '%s'", h.Name("dir/a.txt"), name)
+	}
+	if cache.hash["dir/a.txt"] == "" || cache.base[name] != "dir/a.txt" {
+		t.Fatalf("digest should be stored in the cache, have %v %v", cache.hash, cache.base)
+	}
+	// Another instance reuses the digests without hashing.
+	done := make(chan struct{})
+	close(done)
+	c := &countFS{MapFS: m, release: done}
+	g := New(c, WithCache(cache))
+	if g.Name("dir/a.txt") != name {
+		t.Errorf("Name\nhave '%s'\nwant '%s'", g.Name("dir/a.txt"), name)
+	}
+	base, ok := g.Base(name)
+	if !ok || base != "dir/a.txt" {
+		t.Errorf("Base\nhave '%s'\nwant '%s'", base, "dir/a.txt")
+	}
+	if c.opens != 0 {
+		t.Errorf("cached digests should not be recomputed, have %d opens", c.opens)
+	}
+	sub, err := fs.Sub(g, "dir")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, err := fs.ReadFile(sub, "a.ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb.txt")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(b) != "a" {
+		t.Errorf("content\nhave %q\nwant %q", b, "a")
+	}
+}
@@ -0,0 +1,131 @@
+package hashfs
+
+import (
+	"bytes"
+	"container/list"
+	"io/fs"
+	"sync"
+)
+
+// WithContentCache keeps the contents of validated files of at most
+// maxBytes in memory, evicting the least recently used files once the
+// total cached bytes exceed maxBytes. Panics if maxBytes is not positive.
+func WithContentCache(maxBytes int64) Option {
+	if maxBytes <= 0 {
+		panic("hashfs: content cache size must be positive")
+	}
+	return func(f *FS) {
+		f.content = &contentCache{
+			max:   maxBytes,
+			ll:    list.New(),
+			items: make(map[string]*list.Element),
+		}
+	}
+}
+
+// contentCache is a size bounded LRU cache of file contents.
+// A nil contentCache caches nothing.
+type contentCache struct {
+	mu    sync.Mutex
+	max   int64
+	size  int64
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+// content represents a cached file.
+type content struct {
+	name string
+	data []byte
+	info fs.FileInfo
+}
+
+// fits reports whether a file of the given size may be cached.
+func (c *contentCache) fits(info fs.FileInfo) bool {
+	return c != nil && info.Mode().IsRegular() && info.Size() <= c.max
+}
+
+// get returns the cached contents of the named file.
+func (c *contentCache) get(name string) (*content, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.items[name]
+	if !ok {
+		return nil, false
+	}
+	c.ll.MoveToFront(e)
+	return e.Value.(*content), true
+}
+
+// add caches the contents of the named file, evicting the least
+// recently used files as necessary.
+func (c *contentCache) add(name string, data []byte, info fs.FileInfo) {
+	if c == nil || int64(len(data)) > c.max {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.remove(name)
+	c.items[name] = c.ll.PushFront(&content{name: name, data: data, info: info})
+	c.size += int64(len(data))
+	for c.size > c.max {
+		c.remove(c.ll.Back().Value.(*content).name)
+	}
+}
+
+// delete drops the named file.
+func (c *contentCache) delete(name string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.remove(name)
+}
+
+// reset drops all files.
+func (c *contentCache) reset() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.size = 0
+	c.ll.Init()
+	c.items = make(map[string]*list.Element)
+}
+
+// remove drops the named file. The lock must be held.
+func (c *contentCache) remove(name string) {
+	e, ok := c.items[name]
+	if !ok {
+		return
+	}
+	c.ll.Remove(e)
+	delete(c.items, name)
+	c.size -= int64(len(e.Value.(*content).data))
+}
+
+// memFile is a fs.File backed by cached contents.
+type memFile struct {
+	*bytes.Reader
+	info fs.FileInfo
+}
+
+// newMemFile returns a new fs.File reading from c.
+func newMemFile(c *content) *memFile {
+	return &memFile{Reader: bytes.NewReader(c.data), info: c.info}
+}
+
+// Stat implements the fs.File interface.
+func (f *memFile) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+// Close implements the fs.File interface.
+func (f *memFile) Close() error {
+	return nil
+}
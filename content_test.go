@@ -0,0 +1,85 @@
+package hashfs
+
+import (
+	"io"
+	"testing"
+	"testing/fstest"
+)
+
+func TestContentCache(t *testing.T) {
+	m := fstest.MapFS{
+		"a.txt": &fstest.MapFile{Data: []byte("aaaa")},
+		"b.txt": &fstest.MapFile{Data: []byte("bbbb")},
+		"c.txt": &fstest.MapFile{Data: []byte("cccccccccc")},
+	}
+	h := New(m, WithContentCache(8))
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		f, err := h.Open(h.Name(name))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		b, err := io.ReadAll(f)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if string(b) != string(m[name].Data) {
+			t.Errorf("Open(%q)\nhave %q\nwant %q", name, b, m[name].Data)
+		}
+		f.Close()
+	}
+	_, ok := h.content.get("c.txt")
+	if ok {
+		t.Errorf("files larger than the limit should not be cached")
+	}
+	m["a.txt"].Data = []byte("changed")
+	f, err := h.Open(h.Name("a.txt"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer f.Close()
+	if hf, ok := f.(hashedFile); !ok {
+		t.Errorf("Open should report the hashed name")
+	} else if _, ok := hf.File.(*memFile); !ok {
+		t.Errorf("Open should return cached contents")
+	}
+	b, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(b) != "aaaa" {
+		t.Errorf("Open\nhave %q\nwant %q", b, "aaaa")
+	}
+}
+
+func TestContentCacheEviction(t *testing.T) {
+	m := fstest.MapFS{
+		"a.txt": &fstest.MapFile{Data: []byte("aaaa")},
+		"b.txt": &fstest.MapFile{Data: []byte("bbbb")},
+		"c.txt": &fstest.MapFile{Data: []byte("cccc")},
+	}
+	h := New(m, WithContentCache(8))
+	h.Hash("a.txt")
+	h.Hash("b.txt")
+	h.content.get("a.txt")
+	h.Hash("c.txt")
+	if _, ok := h.content.get("b.txt"); ok {
+		t.Errorf("least recently used file should be evicted")
+	}
+	for _, name := range []string{"a.txt", "c.txt"} {
+		if _, ok := h.content.get(name); !ok {
+			t.Errorf("%q should be cached", name)
+		}
+	}
+	if h.content.size != 8 {
+		t.Errorf("size\nhave %d\nwant %d", h.content.size, 8)
+	}
+}
+
+func TestContentCacheInvalid(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("WithContentCache(0) should panic")
+		}
+	}()
+	WithContentCache(0)
+}
@@ -0,0 +1,87 @@
+package hashfs
+
+import (
+	"bytes"
+	"io/fs"
+	"sort"
+	"strings"
+)
+
+// HashDir returns a digest of the regular files in the given directory
+// and its subdirectories, such as to version a bundle of assets as a
+// whole. The digest covers the path of each file relative to dir and the
+// digest of its contents, in lexical order of the paths, so it is stable
+// across runs and platforms. Files skipped by WithSkipFunc are excluded.
+func (f *FS) HashDir(dir string) (string, error) {
+	var names []string
+	err := fs.WalkDir(f.fs, dir, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if f.skip != nil && name != dir && f.skip(name, d) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() {
+			names = append(names, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", f.pathError("hashdir", err)
+	}
+	sort.Strings(names)
+	var buf bytes.Buffer
+	for _, name := range names {
+		hash, err := f.HashErr(name)
+		if err != nil {
+			return "", err
+		}
+		rel := name
+		if dir != "." {
+			rel = strings.TrimPrefix(name, dir+"/")
+		}
+		buf.WriteString(rel)
+		buf.WriteByte(0)
+		buf.WriteString(hash)
+		buf.WriteByte('\n')
+	}
+	if f.impl != nil {
+		return f.impl.Sum(buf.Bytes()), nil
+	}
+	h := f.newHash()
+	h.Write(buf.Bytes())
+	return f.encode(h.Sum(nil)), nil
+}
+
+// Version returns a short digest of the sorted hashed file names of all
+// files, as walked by WalkHashed, that changes whenever any file does.
+// It is deterministic for identical contents, such as for a deploy
+// identifier or a "?v=" parameter of responses that are not assets.
+func (f *FS) Version() (string, error) {
+	const n = 8
+	var names []string
+	err := f.WalkHashed(func(hashedPath string, d fs.DirEntry) error {
+		names = append(names, hashedPath)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(names)
+	b := []byte(strings.Join(names, "\n"))
+	var version string
+	if f.impl != nil {
+		version = f.impl.Sum(b)
+	} else {
+		h := f.newHash()
+		h.Write(b)
+		version = f.enc.encode(h.Sum(nil))
+	}
+	if len(version) > n {
+		version = version[:n]
+	}
+	return version, nil
+}
@@ -0,0 +1,72 @@
+package hashfs
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func TestHashDir(t *testing.T) {
+	m := fstest.MapFS{
+		"bundle/a.txt":    &fstest.MapFile{Data: []byte("a")},
+		"bundle/b/c.txt":  &fstest.MapFile{Data: []byte("c")},
+		"other/a.txt":     &fstest.MapFile{Data: []byte("a")},
+		"other/b/c.txt":   &fstest.MapFile{Data: []byte("c")},
+		"changed/a.txt":   &fstest.MapFile{Data: []byte("b")},
+		"changed/b/c.txt": &fstest.MapFile{Data: []byte("c")},
+	}
+	h := New(m)
+	bundle, err := h.HashDir("bundle")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	other, err := New(m).HashDir("other")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if bundle != other {
+		t.Errorf("HashDir should only depend on relative paths and contents\nhave '%s'\nwant '%s'", other, bundle)
+	}
+	changed, err := h.HashDir("changed")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if changed == bundle {
+		t.Errorf("HashDir should change with file contents")
+	}
+	root, err := h.HashDir(".")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if root == bundle {
+		t.Errorf("HashDir should cover every file")
+	}
+	_, err = h.HashDir("missing")
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("HashDir should yield fs.ErrNotExist, have %v", err)
+	}
+}
+
+func TestVersion(t *testing.T) {
+	m := fstest.MapFS{
+		"a.txt":   &fstest.MapFile{Data: []byte("a")},
+		"b/c.txt": &fstest.MapFile{Data: []byte("c")},
+	}
+	v1, err := New(m).Version()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(v1) != 8 {
+		t.Errorf("Version should be short, have '%s'", v1)
+	}
+	v2, _ := New(m).Version()
+	if v1 != v2 {
+		t.Errorf("Version should be deterministic\nhave '%s'\nwant '%s'", v2, v1)
+	}
+	m["a.txt"] = &fstest.MapFile{Data: []byte("b")}
+	v3, _ := New(m).Version()
+	if v3 == v1 {
+		t.Errorf("Version should change with contents")
+	}
+}
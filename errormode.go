@@ -0,0 +1,71 @@
+package hashfs
+
+import (
+	"io/fs"
+)
+
+// ErrorMode determines how files that could not be hashed are reported.
+type ErrorMode int
+
+// Error modes.
+const (
+	// ErrorModeEmpty returns empty strings from Name, Hash and Integrity
+	// and errors from HashErr and Open as usual.
+	ErrorModeEmpty ErrorMode = iota
+
+	// ErrorModePanic panics with the error instead of returning empty
+	// strings from Name, Hash and Integrity, such as for build steps that
+	// must not proceed with a missing asset.
+	ErrorModePanic
+
+	// ErrorModeWrap returns errors from HashErr and HashContext
+	// as an *AssetError identifying the file.
+	ErrorModeWrap
+)
+
+// WithErrorMode sets how files that could not be hashed are reported.
+// Defaults to ErrorModeEmpty.
+func WithErrorMode(mode ErrorMode) Option {
+	return func(f *FS) {
+		f.errorMode = mode
+	}
+}
+
+// AssetError records the file that could not be hashed
+// and the reason. See ErrorModeWrap.
+type AssetError struct {
+	Name string
+	Err  error
+}
+
+// Error implements the error interface.
+func (e *AssetError) Error() string {
+	return "hashfs: asset " + e.Name + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *AssetError) Unwrap() error {
+	return e.Err
+}
+
+// wrapError returns err as an *AssetError if set by WithErrorMode.
+func (f *FS) wrapError(name string, err error) error {
+	if f.errorMode != ErrorModeWrap {
+		return err
+	}
+	return &AssetError{Name: name, Err: err}
+}
+
+// fail panics with the reason the given file could not
+// be hashed if set by WithErrorMode.
+func (f *FS) fail(name string) {
+	if f.errorMode != ErrorModePanic {
+		return
+	}
+	name, _ = splitQuery(name)
+	_, err := f.HashErr(name)
+	if err == nil {
+		err = &fs.PathError{Op: "hash", Path: name, Err: fs.ErrNotExist}
+	}
+	panic(err)
+}
@@ -0,0 +1,38 @@
+package hashfs
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+)
+
+func TestWithErrorMode(t *testing.T) {
+	h := New(testdata, WithErrorMode(ErrorModeEmpty))
+	if h.Name("missing.ext") != "" || h.Hash("missing.ext") != "" {
+		t.Errorf("ErrorModeEmpty should return empty strings")
+	}
+	h = New(testdata, WithErrorMode(ErrorModePanic))
+	for i, fn := range []func(string) string{h.Name, h.Hash, h.Integrity} {
+		func() {
+			defer func() {
+				r := recover()
+				err, ok := r.(error)
+				if !ok || !errors.Is(err, fs.ErrNotExist) {
+					t.Errorf("%d. ErrorModePanic should panic with fs.ErrNotExist, have %v", i, r)
+				}
+			}()
+			fn("missing.ext")
+		}()
+	}
+	if h.NameOrDefault("missing.ext") != "missing.ext" {
+		t.Errorf("NameOrDefault should not panic")
+	}
+	_, err := New(testdata, WithErrorMode(ErrorModeWrap)).HashErr("missing.ext")
+	var e *AssetError
+	if !errors.As(err, &e) || e.Name != "missing.ext" {
+		t.Fatalf("ErrorModeWrap should yield an *AssetError, have %v", err)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("AssetError should wrap fs.ErrNotExist, have %v", err)
+	}
+}
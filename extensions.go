@@ -0,0 +1,80 @@
+package hashfs
+
+import (
+	"path"
+	"strings"
+)
+
+// WithExtensions restricts hashing to files with the given extensions,
+// such as ".js" and ".css". Name returns the names of other files
+// unchanged, WarmCache skips them and Open serves them by plain name
+// only, even if WithStrictHashed is set.
+func WithExtensions(exts ...string) Option {
+	return func(f *FS) {
+		f.exts = make(map[string]bool, len(exts))
+		for _, ext := range exts {
+			if !strings.HasPrefix(ext, ".") {
+				ext = "." + ext
+			}
+			f.exts[strings.ToLower(ext)] = true
+		}
+	}
+}
+
+// WithDisabled turns the FS into a passthrough for development if
+// disabled is true. As with files excluded by WithExtensions, Name
+// returns names unchanged apart from the URL prefix, WarmCache skips
+// every file and Open serves plain names only.
+func WithDisabled(disabled bool) Option {
+	return func(f *FS) {
+		f.disabled = disabled
+	}
+}
+
+// hashable reports whether the given file is hashed
+// per WithExtensions and WithDisabled.
+func (f *FS) hashable(name string) bool {
+	if f.disabled {
+		return false
+	}
+	if f.exts == nil {
+		return true
+	}
+	return f.exts[strings.ToLower(path.Ext(name))]
+}
+
+// WithExtSegments sets the number of trailing extension segments that
+// follow the digest in the Suffix format and "{ext}" of name templates.
+// For example, with 2 segments "app.chunk.js" becomes
+// "app.<hash>.chunk.js". Defaults to 1. Panics if n is less than 1.
+func WithExtSegments(n int) Option {
+	if n < 1 {
+		panic("hashfs: invalid extension segments")
+	}
+	return func(f *FS) {
+		f.extSegments = n
+	}
+}
+
+// ext returns the extension of the given file name, consisting of
+// up to the configured number of trailing extension segments.
+func (f *FS) ext(name string) string {
+	return extSegments(name, f.extSegments)
+}
+
+// extSegments returns up to n trailing extension segments of
+// the given file name. Returns path.Ext if n is less than 2.
+func extSegments(name string, n int) string {
+	if n < 2 {
+		return path.Ext(name)
+	}
+	i := len(name)
+	for ; n > 0; n-- {
+		j := strings.LastIndexAny(name[:i], "./")
+		if j < 0 || name[j] != '.' {
+			break
+		}
+		i = j
+	}
+	return name[i:]
+}
@@ -0,0 +1,101 @@
+package hashfs
+
+import (
+	"io"
+	"testing"
+	"testing/fstest"
+)
+
+func TestWithExtensions(t *testing.T) {
+	m := fstest.MapFS{
+		"a.js":    &fstest.MapFile{Data: []byte("a")},
+		"a.woff2": &fstest.MapFile{Data: []byte("a")},
+	}
+	h := New(m, WithExtensions("JS"), WithHashLength(8), WithStrictHashed(true))
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"a.js", "a.ca978112.js"},
+		{"a.woff2", "a.woff2"},
+	}
+	for _, tt := range tests {
+		have := h.Name(tt.name)
+		if have != tt.want {
+			t.Errorf("Name(%q)\nhave '%s'\nwant '%s'", tt.name, have, tt.want)
+		}
+	}
+	err := h.WarmCache()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, ok := h.getHash("a.woff2")
+	if ok {
+		t.Errorf("WarmCache should skip unlisted extensions")
+	}
+	for _, name := range []string{"a.ca978112.js", "a.woff2"} {
+		file, err := h.Open(name)
+		if err != nil {
+			t.Errorf("Open(%q): %v", name, err)
+			continue
+		}
+		b, _ := io.ReadAll(file)
+		file.Close()
+		if string(b) != "a" {
+			t.Errorf("Open(%q)\nhave %q\nwant %q", name, b, "a")
+		}
+	}
+	for _, name := range []string{"a.js", "a.ca978112.woff2"} {
+		_, err := h.Open(name)
+		if err == nil {
+			t.Errorf("Open(%q) should error", name)
+		}
+	}
+}
+
+func TestWithDisabled(t *testing.T) {
+	m := fstest.MapFS{"a.js": &fstest.MapFile{Data: []byte("a")}}
+	tests := []struct {
+		disabled bool
+		want     string
+	}{
+		{true, "/static/a.js"},
+		{false, "/static/a.ca978112.js"},
+	}
+	for _, tt := range tests {
+		h := New(m, WithDisabled(tt.disabled), WithHashLength(8), WithURLPrefix("/static/"), WithStrictHashed(true))
+		have := h.Name("a.js")
+		if have != tt.want {
+			t.Errorf("%t Name\nhave '%s'\nwant '%s'", tt.disabled, have, tt.want)
+		}
+		file, err := h.Open(have)
+		if err != nil {
+			t.Errorf("%t Open(%q): %v", tt.disabled, have, err)
+			continue
+		}
+		file.Close()
+	}
+	h := New(m, WithDisabled(true), WithHashLength(8))
+	_, err := h.Open("a.ca978112.js")
+	if err == nil {
+		t.Errorf("Open should not resolve hashed names when disabled")
+	}
+}
+
+func TestWithExtensionsCached(t *testing.T) {
+	m := fstest.MapFS{"a.woff": &fstest.MapFile{Data: []byte("a")}}
+	tests := []Option{WithExtensions(".js"), WithDisabled(true)}
+	for i, opt := range tests {
+		h := New(m, opt)
+		if h.Integrity("a.woff") == "" {
+			t.Fatalf("%d. Integrity should hash files that are not hashable", i)
+		}
+		if h.Name("a.woff") != "a.woff" {
+			t.Errorf("%d. Name\nhave '%s'\nwant '%s'", i, h.Name("a.woff"), "a.woff")
+		}
+		_, err := h.Open(New(m).Name("a.woff"))
+		if err == nil {
+			t.Errorf("%d. Open should not resolve hashed names of files that are not hashable", i)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package hashfs
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// call represents an in-flight digest computation.
+type call struct {
+	wg   sync.WaitGroup
+	hash string
+	sum  []byte
+	err  error
+}
+
+// group deduplicates concurrent digest computations of the
+// same file so that the file is only read once. The zero
+// value is ready for use.
+type group struct {
+	mu sync.Mutex
+	m  map[string]*call
+}
+
+// do calls fn for the given file name unless a call for the same name
+// is already in flight, in which case it waits for and returns its
+// results. If the call fails due to its own context while ctx, the
+// context of the waiter, is still live, the waiter calls fn itself.
+func (g *group) do(ctx context.Context, name string, fn func() (string, []byte, error)) (string, []byte, error) {
+	for {
+		g.mu.Lock()
+		if g.m == nil {
+			g.m = make(map[string]*call)
+		}
+		c, ok := g.m[name]
+		if !ok {
+			break
+		}
+		g.mu.Unlock()
+		c.wg.Wait()
+		if isContextError(c.err) && ctx.Err() == nil {
+			continue
+		}
+		return c.hash, c.sum, c.err
+	}
+	c := &call{}
+	c.wg.Add(1)
+	g.m[name] = c
+	g.mu.Unlock()
+	c.hash, c.sum, c.err = fn()
+	g.mu.Lock()
+	delete(g.m, name)
+	g.mu.Unlock()
+	c.wg.Done()
+	return c.hash, c.sum, c.err
+}
+
+// isContextError reports whether err is due to a canceled
+// or expired context.
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,123 @@
+package hashfs
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"io/fs"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// countFS is a fs.FS that counts opens and blocks them until released.
+type countFS struct {
+	fstest.MapFS
+	opens   int32
+	release chan struct{}
+}
+
+// Open implements the fs.FS interface.
+func (f *countFS) Open(name string) (fs.File, error) {
+	atomic.AddInt32(&f.opens, 1)
+	<-f.release
+	return f.MapFS.Open(name)
+}
+
+func TestSingleFlight(t *testing.T) {
+	m := &countFS{
+		MapFS:   fstest.MapFS{"a.txt": &fstest.MapFile{Data: []byte("a")}},
+		release: make(chan struct{}),
+	}
+	h := New(m)
+	want := "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb"
+	name := "a." + want + ".txt"
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			hash := h.Hash("a.txt")
+			if hash != want {
+				t.Errorf("Hash\nhave '%s'\nwant '%s'", hash, want)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			_, ok := h.Base(name)
+			if !ok {
+				t.Errorf("Base(%q) should resolve", name)
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(m.release)
+	wg.Wait()
+	opens := atomic.LoadInt32(&m.opens)
+	if opens != 1 {
+		t.Errorf("file should be read once, have %d reads", opens)
+	}
+}
+
+func TestOpenCachesName(t *testing.T) {
+	release := make(chan struct{})
+	close(release)
+	m := &countFS{
+		MapFS:   fstest.MapFS{"a.txt": &fstest.MapFile{Data: []byte("a")}},
+		release: release,
+	}
+	tests := []struct {
+		h    *FS
+		name string
+	}{
+		{New(m), "a.ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb.txt"},
+		{New(m), "a.8888888888888888888888888888888888888888888888888888888888888888.txt"},
+		{New(m, WithHashers(sha512.New, sha256.New)), "a.ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb.txt"},
+	}
+	for _, tt := range tests {
+		tt.h.Base(tt.name)
+		opens := atomic.LoadInt32(&m.opens)
+		if tt.h.Name("a.txt") == "" {
+			t.Errorf("Name should succeed")
+		}
+		if atomic.LoadInt32(&m.opens) != opens {
+			t.Errorf("Name after resolving %q should not read the file again", tt.name)
+		}
+	}
+}
+
+func TestSingleFlightCanceled(t *testing.T) {
+	m := &countFS{
+		MapFS:   fstest.MapFS{"a.txt": &fstest.MapFile{Data: []byte("a")}},
+		release: make(chan struct{}),
+	}
+	h := New(m)
+	ctx, cancel := context.WithCancel(context.Background())
+	canceled := make(chan error, 1)
+	go func() {
+		_, err := h.HashContext(ctx, "a.txt")
+		canceled <- err
+	}()
+	for atomic.LoadInt32(&m.opens) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, err := h.HashErr("a.txt")
+		done <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	close(m.release)
+	err := <-canceled
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("HashContext\nhave %v\nwant %v", err, context.Canceled)
+	}
+	err = <-done
+	if err != nil {
+		t.Errorf("HashErr should not share the cancellation of another call, have %v", err)
+	}
+}
@@ -1,3 +1,8 @@
 module github.com/pnelson/hashfs
 
 go 1.16
+
+require (
+	github.com/andybalholm/brotli v1.1.0
+	github.com/fsnotify/fsnotify v1.5.4
+)
@@ -0,0 +1,53 @@
+package hashfs
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+)
+
+// errNoRawHash is returned when raw digests are not available.
+var errNoRawHash = errors.New("hashfs: raw digest not available with a custom Hasher")
+
+// Hasher computes file name ready digests of file contents.
+type Hasher interface {
+	// Sum returns the encoded digest of content.
+	Sum(content []byte) string
+
+	// Verify reports whether encoded is a valid digest of content.
+	Verify(content []byte, encoded string) bool
+}
+
+// WithHasherImpl sets the Hasher used to compute and verify digests,
+// taking precedence over the hash.Hash and encoding options. Raw
+// digests are not available when a Hasher is configured and
+// Integrity always computes sha256 digests.
+func WithHasherImpl(h Hasher) Option {
+	return func(f *FS) {
+		f.impl = h
+		f.alg = ""
+	}
+}
+
+// resolveImpl verifies the candidate digest of the given hashed
+// file name and base file name using the configured Hasher.
+func (f *FS) resolveImpl(name, base, want string) (string, error) {
+	b, err := f.readFile(context.Background(), base)
+	if err != nil {
+		var e *fs.PathError
+		if errors.As(err, &e) {
+			return "", e.Err
+		}
+		return "", err
+	}
+	b = f.textContent(base, b)
+	if !f.impl.Verify(b, want) {
+		return "", ErrHashMismatch
+	}
+	err = f.setHash(base, f.impl.Sum(b), nil)
+	if err != nil {
+		return "", err
+	}
+	f.setBase(name, base)
+	return base, nil
+}
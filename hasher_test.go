@@ -0,0 +1,58 @@
+package hashfs
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// blobHasher computes git blob digests and accepts abbreviations.
+type blobHasher struct{}
+
+// Sum implements the Hasher interface.
+func (blobHasher) Sum(content []byte) string {
+	h := sha1.New()
+	h.Write([]byte("blob " + strconv.Itoa(len(content)) + "\x00"))
+	h.Write(content)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Verify implements the Hasher interface.
+func (b blobHasher) Verify(content []byte, encoded string) bool {
+	return len(encoded) >= 7 && strings.HasPrefix(b.Sum(content), encoded)
+}
+
+func TestHasherImpl(t *testing.T) {
+	h := New(testdata, WithHasherImpl(blobHasher{}))
+	name := h.Name("testdata/base.ext")
+	want := "testdata/base." + blobHasher{}.Sum([]byte("base.ext\n")) + ".ext"
+	if name != want {
+		t.Errorf("Name\nhave '%s'\nwant '%s'", name, want)
+	}
+	tests := []string{
+		want,
+		"testdata/base." + blobHasher{}.Sum([]byte("base.ext\n"))[:7] + ".ext",
+	}
+	for _, tt := range tests {
+		f, err := New(testdata, WithHasherImpl(blobHasher{})).Open(tt)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			continue
+		}
+		f.Close()
+	}
+	_, err := h.Open("testdata/base.8888888.ext")
+	if !errors.Is(err, ErrHashMismatch) {
+		t.Errorf("Open should yield ErrHashMismatch, have %v", err)
+	}
+	_, err = h.RawHash("testdata/base.ext")
+	if err == nil {
+		t.Errorf("RawHash should error with a custom Hasher")
+	}
+	if h.HashBytes("bundle.css", []byte("a")) != (blobHasher{}).Sum([]byte("a")) {
+		t.Errorf("HashBytes should use the custom Hasher")
+	}
+}
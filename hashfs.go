@@ -2,113 +2,1365 @@
 package hashfs
 
 import (
+	"bytes"
+	"context"
 	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
 	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
 	"io/fs"
+	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 )
 
 // FS is a fs.FS implementation that appends
 // sha256 digests to the filenames.
 type FS struct {
-	mu   sync.RWMutex
-	fs   fs.FS
-	hash map[string]string // ["base.ext"] => "hash"
-	base map[string]string // ["base.hash.ext"] => "base.ext"
+	mu      sync.RWMutex
+	fs      fs.FS
+	hash    map[string]string // ["base.ext"] => "hash"
+	base    map[string]string // ["base.hash.ext"] => "base.ext"
+	name    map[string]string // ["base.ext"] => "prefix/base.hash.ext"
+	sum     map[string][]byte // ["base.ext"] => digest
+	newHash func() hash.Hash
+	alt     []func() hash.Hash // accepted by Open for migrations
+	alg     string             // integrity algorithm of newHash, if known
+	length  int                // truncated digest length, zero for full
+	enc     Encoding
+	content *contentCache
+	verify  bool
+	layout  Format
+	sep     string
+	opts    []Option
+	dir     string // os directory, if known
+	obs     Observer
+	log     logger
+	impl    Hasher
+	flight  group
+	fold    bool                // case-insensitive digests
+	index   map[string][]string // ["hash"] => ["base.ext"]
+	stats   map[string]FileStat // ["base.ext"] => stat
+	skip    func(string, fs.DirEntry) bool
+	cache   Cache  // shared between instances
+	prefix  string // URL prefix of hashed names
+
+	modcheck bool
+	mtime    map[string]time.Time // ["base.ext"] => modification time
+
+	tmpl     string
+	tmplRe   *regexp.Regexp
+	maxSize  int64
+	strict   bool
+	constant bool   // constant time digest comparison
+	adaptive bool   // length is a minimum
+	xattr    string // extended attribute of digests
+	polling  int32  // active StartPolling calls
+
+	indexFile  string
+	preload    *preload
+	policy     map[string]string // [".ext"] => Cache-Control
+	exts       map[string]bool   // [".ext"] => hashable
+	disabled   bool
+	sizeInName bool
+	errorMode  ErrorMode
+	textExts   map[string]bool // [".ext"] => normalized line endings
+
+	extSegments int
+
+	compressors []compressor
+	variants    map[string]map[string][]byte // ["base.ext"]["gzip"] => compressed
+}
+
+// ErrHashMismatch is returned when the digest in a hashed
+// file name does not match the contents of the file.
+var ErrHashMismatch = errors.New("hashfs: hash mismatch")
+
+// ErrCollision is returned when two files would have the same hashed
+// file name, such as with truncated digests.
+var ErrCollision = errors.New("hashfs: hashed file name collision")
+
+// ErrTooLarge is returned when a file exceeds the size set by WithMaxSize.
+var ErrTooLarge = errors.New("hashfs: file too large")
+
+// Encoding represents a file name safe digest encoding.
+type Encoding int
+
+// Encodings.
+const (
+	Hex       Encoding = iota // lowercase hexadecimal
+	Base64URL                 // unpadded URL-safe base64
+)
+
+// encode returns the encoded digest.
+func (e Encoding) encode(digest []byte) string {
+	if e == Base64URL {
+		return base64.RawURLEncoding.EncodeToString(digest)
+	}
+	return hex.EncodeToString(digest)
+}
+
+// encodedLen returns the length of an encoding of n bytes.
+func (e Encoding) encodedLen(n int) int {
+	if e == Base64URL {
+		return base64.RawURLEncoding.EncodedLen(n)
+	}
+	return hex.EncodedLen(n)
 }
 
+// alphabet returns the characters used by the encoding.
+func (e Encoding) alphabet() string {
+	if e == Base64URL {
+		return "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_"
+	}
+	return "0123456789abcdef"
+}
+
+// Format represents the placement of digests in file names.
+type Format int
+
+// Formats.
+const (
+	Suffix    Format = iota // base.hash.ext
+	PrefixDir               // hash/base.ext
+)
+
+// Option represents a functional option for configuration.
+type Option func(*FS)
+
 // New returns a new hashing fs.FS implementation.
-func New(fs fs.FS) *FS {
-	return &FS{
-		fs:   fs,
-		hash: make(map[string]string),
-		base: make(map[string]string),
+// New panics if fs is nil.
+func New(fs fs.FS, opts ...Option) *FS {
+	if fs == nil {
+		panic("hashfs: nil fs.FS")
+	}
+	f := &FS{
+		fs:       fs,
+		hash:     make(map[string]string),
+		base:     make(map[string]string),
+		name:     make(map[string]string),
+		sum:      make(map[string][]byte),
+		variants: make(map[string]map[string][]byte),
+		stats:    make(map[string]FileStat),
+		mtime:    make(map[string]time.Time),
+		newHash:  sha256.New,
+		alg:      "sha256",
+		sep:      ".",
+		opts:     opts,
+	}
+	for _, option := range opts {
+		option(f)
+	}
+	if f.tmpl != "" {
+		f.tmplRe = f.templateRegexp()
+	}
+	if f.impl != nil {
+		// Encoding is entirely up to the Hasher.
+		return f
 	}
+	for _, fn := range append([]func() hash.Hash{f.newHash}, f.alt...) {
+		if f.length > f.enc.encodedLen(fn().Size()) {
+			panic("hashfs: hash length exceeds digest length")
+		}
+	}
+	if strings.ContainsAny(f.sep, f.enc.alphabet()) {
+		panic("hashfs: separator conflicts with digest encoding")
+	}
+	return f
 }
 
-// Hash returns the sha256 digest of the given file.
+// NewFromDir returns a new hashing fs.FS implementation for the
+// files rooted at the given directory. Errors include the directory.
+func NewFromDir(dir string, opts ...Option) *FS {
+	f := New(os.DirFS(dir), opts...)
+	f.dir = dir
+	return f
+}
+
+// WithHasher sets the hash.Hash constructor used to compute digests.
+// Defaults to sha256.New.
+func WithHasher(fn func() hash.Hash) Option {
+	return func(f *FS) {
+		f.newHash = fn
+		f.alg = ""
+	}
+}
+
+// WithHashers sets multiple hash.Hash constructors. The first is used
+// to compute digests and Open accepts digests matching any of them,
+// allowing migrations between algorithms. Panics if fns is empty.
+func WithHashers(fns ...func() hash.Hash) Option {
+	if len(fns) == 0 {
+		panic("hashfs: no hashers")
+	}
+	return func(f *FS) {
+		f.newHash = fns[0]
+		f.alt = fns[1:]
+		f.alg = ""
+	}
+}
+
+// WithEncoding sets the encoding used for digests in file names.
+// Defaults to Hex.
+func WithEncoding(enc Encoding) Option {
+	return func(f *FS) {
+		f.enc = enc
+	}
+}
+
+// WithFormat sets the placement of digests in file names.
+// Defaults to Suffix.
+func WithFormat(format Format) Option {
+	return func(f *FS) {
+		f.layout = format
+	}
+}
+
+// WithSeparator sets the separator between the base name and the digest
+// in the Suffix format. Defaults to ".". Panics if sep is empty or is not
+// a legal file name character.
+func WithSeparator(sep string) Option {
+	if sep == "" || strings.ContainsAny(sep, "/\\<>:\"|?*") {
+		panic("hashfs: invalid separator")
+	}
+	for _, r := range sep {
+		if r < ' ' || r == 0x7f {
+			panic("hashfs: invalid separator")
+		}
+	}
+	return func(f *FS) {
+		f.sep = sep
+	}
+}
+
+// WithCaseInsensitive makes Open accept hex digests regardless of case,
+// such as "APP.<HASH>.js". Name always emits lowercase hex digests.
+// Base64URL digests are case-sensitive and unaffected.
+func WithCaseInsensitive(fold bool) Option {
+	return func(f *FS) {
+		f.fold = fold
+	}
+}
+
+// WithHashLength truncates the encoded digests to the first n characters.
+// Panics if n is not positive or exceeds the encoded digest length.
+func WithHashLength(n int) Option {
+	if n <= 0 {
+		panic("hashfs: hash length must be positive")
+	}
+	return func(f *FS) {
+		f.length = n
+	}
+}
+
+// WithURLPrefix sets a prefix, such as "/static/", that Name prepends
+// to hashed file names and that Open removes from requested names.
+// A trailing slash is added if missing so the prefix only matches
+// whole path segments.
+func WithURLPrefix(prefix string) Option {
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return func(f *FS) {
+		f.prefix = prefix
+	}
+}
+
+// WithMaxSize refuses to hash files larger than n bytes, returning
+// ErrTooLarge instead. WarmCache skips such files, notifying the
+// observer if it implements SkipObserver. Panics if n is not positive.
+func WithMaxSize(n int64) Option {
+	if n <= 0 {
+		panic("hashfs: max size must be positive")
+	}
+	return func(f *FS) {
+		f.maxSize = n
+	}
+}
+
+// WithStrictHashed makes Open, ReadFile and Stat reject the plain names
+// of files and directories with fs.ErrNotExist, such as to catch
+// references that are missing a call to Name.
+func WithStrictHashed(strict bool) Option {
+	return func(f *FS) {
+		f.strict = strict
+	}
+}
+
+// WithConstantTimeCompare compares the digests in hashed
+// file names against file contents in constant time.
+func WithConstantTimeCompare(enabled bool) Option {
+	return func(f *FS) {
+		f.constant = enabled
+	}
+}
+
+// WithVerifyOnOpen forces hashed file names to be verified against
+// the file contents on every open, even if previously resolved.
+func WithVerifyOnOpen(verify bool) Option {
+	return func(f *FS) {
+		f.verify = verify
+	}
+}
+
+// Hash returns the digest of the given file. Returns an empty
+// string if the file could not be hashed, or panics per WithErrorMode.
 func (f *FS) Hash(name string) string {
+	hash, err := f.HashErr(name)
+	if err != nil && f.errorMode == ErrorModePanic {
+		panic(err)
+	}
+	return hash
+}
+
+// HashErr returns the digest of the given file or
+// the error encountered while reading it.
+func (f *FS) HashErr(name string) (string, error) {
+	return f.HashContext(context.Background(), name)
+}
+
+// HashContext is like HashErr but stops reading the file
+// once the context is done, returning the context error.
+func (f *FS) HashContext(ctx context.Context, name string) (string, error) {
 	hash, ok := f.getHash(name)
-	if ok {
+	if ok && !f.stale(name) {
+		if f.obs != nil {
+			f.obs.CacheHit(name)
+		}
+		return hash, nil
+	}
+	hash, sum, err := f.makeHash(ctx, name)
+	if err != nil {
+		return "", f.wrapError(name, f.pathError("hash", err))
+	}
+	err = f.setHash(name, hash, sum)
+	if err != nil {
+		return "", f.wrapError(name, &fs.PathError{Op: "hash", Path: name, Err: err})
+	}
+	return hash, nil
+}
+
+// pathError returns err with the given operation if it is a
+// *fs.PathError, prepending the directory to the path if known.
+func (f *FS) pathError(op string, err error) error {
+	e, ok := err.(*fs.PathError)
+	if !ok {
+		return err
+	}
+	name := e.Path
+	if f.dir != "" {
+		name = filepath.Join(f.dir, filepath.FromSlash(name))
+	}
+	return &fs.PathError{Op: op, Path: name, Err: e.Err}
+}
+
+// HashBytes returns the digest of the given content and caches it
+// under the given file name so that it participates in Name.
+func (f *FS) HashBytes(name string, content []byte) string {
+	content = f.textContent(name, content)
+	if f.impl != nil {
+		hash := f.impl.Sum(content)
+		f.setHash(name, hash, nil)
 		return hash
 	}
-	ext := filepath.Ext(name)
-	hash = f.makeHash(name)
-	if hash == "" {
+	h := f.newHash()
+	h.Write(content)
+	sum := h.Sum(nil)
+	hash := f.encode(sum)
+	f.setHash(name, hash, sum)
+	return hash
+}
+
+// HashReader is like HashBytes but streams the content from r, such as
+// content from a database or network that is not in any file system.
+// The reader is consumed. Returns ErrCollision if the hashed file name is
+// already used by another file.
+func (f *FS) HashReader(name string, r io.Reader) (string, error) {
+	var hash string
+	var sum []byte
+	if f.impl != nil {
+		b, err := io.ReadAll(r)
+		if err != nil {
+			return "", &fs.PathError{Op: "hashreader", Path: name, Err: err}
+		}
+		hash = f.impl.Sum(f.textContent(name, b))
+	} else {
+		h := f.newHash()
+		w := &lfWriter{w: h}
+		var err error
+		if f.normalizesText(name) {
+			_, err = io.Copy(w, r)
+			if err == nil {
+				err = w.flush()
+			}
+		} else {
+			_, err = io.Copy(h, r)
+		}
+		if err != nil {
+			return "", &fs.PathError{Op: "hashreader", Path: name, Err: err}
+		}
+		sum = h.Sum(nil)
+		hash = f.encode(sum)
+	}
+	err := f.setHash(name, hash, sum)
+	if err != nil {
+		return "", err
+	}
+	return hash, nil
+}
+
+// RawHash returns a copy of the full raw digest of the given file.
+func (f *FS) RawHash(name string) ([]byte, error) {
+	sum, err := f.rawHash(name)
+	if err != nil {
+		return nil, f.pathError("rawhash", err)
+	}
+	return append([]byte(nil), sum...), nil
+}
+
+// ShortID returns a short identifier of the digest of the given file,
+// the first 8 hex characters of the full raw digest, for logging. The
+// hashed file name is unaffected. Uses the encoded digest if raw digests
+// are not available. Returns an empty string if the file could not be
+// hashed.
+func (f *FS) ShortID(name string) string {
+	const n = 8
+	sum, err := f.rawHash(name)
+	if err == errNoRawHash {
+		id := f.Hash(name)
+		if len(id) > n {
+			id = id[:n]
+		}
+		return id
+	}
+	if err != nil {
 		return ""
 	}
-	base := name[:len(name)-len(ext)] + "." + hash + ext
+	id := hex.EncodeToString(sum)
+	if len(id) > n {
+		id = id[:n]
+	}
+	return id
+}
+
+// rawHash returns the cached raw digest of the given file,
+// computing it if necessary. The result must not be modified.
+func (f *FS) rawHash(name string) ([]byte, error) {
+	if f.impl != nil {
+		return nil, errNoRawHash
+	}
+	f.mu.RLock()
+	sum, ok := f.sum[name]
+	f.mu.RUnlock()
+	if ok {
+		return sum, nil
+	}
+	_, ok = f.getHash(name)
+	if !ok {
+		hash, sum, err := f.makeHash(context.Background(), name)
+		if err != nil {
+			return nil, err
+		}
+		err = f.setHash(name, hash, sum)
+		if err != nil {
+			return nil, &fs.PathError{Op: "hash", Path: name, Err: err}
+		}
+		if sum != nil {
+			return sum, nil
+		}
+	}
+	// Loaded digests and those read from extended
+	// attributes have no raw digest to reuse.
+	sum, err := f.textDigest(context.Background(), name, f.newHash())
+	if err != nil {
+		return nil, err
+	}
 	f.mu.Lock()
-	f.hash[name] = hash
-	f.base[base] = name
+	f.sum[name] = sum
 	f.mu.Unlock()
-	return hash
+	return sum, nil
 }
 
-// getHash performs a synchronized lookup on the hash map.
+// getHash performs a synchronized lookup on the hash map,
+// falling back to the shared cache, if any.
 func (f *FS) getHash(name string) (string, bool) {
 	f.mu.RLock()
-	defer f.mu.RUnlock()
 	hash, ok := f.hash[name]
+	f.mu.RUnlock()
+	if ok || f.cache == nil {
+		return hash, ok
+	}
+	hash, ok = f.cache.GetHash(name)
+	if ok {
+		f.storeHash(name, hash, nil)
+	}
 	return hash, ok
 }
 
-// makeHash returns the full sha256 digest for the given file name.
-func (f *FS) makeHash(name string) string {
-	b, err := fs.ReadFile(f.fs, name)
+// setHash performs a synchronized update on the cache maps
+// and the shared cache, if any.
+func (f *FS) setHash(name, hash string, sum []byte) error {
+	err := f.storeHash(name, hash, sum)
 	if err != nil {
-		return ""
+		return err
+	}
+	if f.cache != nil {
+		f.cache.SetHash(name, hash)
+		if f.hashable(name) {
+			f.cache.SetBase(f.format(name, hash), name)
+		}
+	}
+	return nil
+}
+
+// storeHash performs a synchronized update on the cache maps.
+// Returns ErrCollision if the hashed file name already
+// resolves to another file. Files that are not hashable
+// only have their digests recorded.
+func (f *FS) storeHash(name, hash string, sum []byte) error {
+	hashable := f.hashable(name)
+	hashed := f.format(name, hash)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	other, ok := f.base[hashed]
+	if hashable && ok && other != name {
+		return ErrCollision
+	}
+	old, ok := f.hash[name]
+	if ok {
+		f.removeIndex(name, old)
+		delete(f.base, strings.TrimPrefix(f.name[name], f.prefix))
+	}
+	f.hash[name] = hash
+	if hashable {
+		f.base[hashed] = name
+		f.name[name] = f.prefix + hashed
+		f.addIndex(name, hash)
+	} else {
+		delete(f.name, name)
+	}
+	if sum != nil {
+		f.sum[name] = sum
+	}
+	return nil
+}
+
+// Invalidate drops the cached digest of the given file so
+// that it is recomputed on next use.
+func (f *FS) Invalidate(name string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.removeIndex(name, f.hash[name])
+	delete(f.hash, name)
+	delete(f.name, name)
+	delete(f.sum, name)
+	delete(f.variants, name)
+	delete(f.stats, name)
+	delete(f.mtime, name)
+	for k, v := range f.base {
+		if v == name {
+			delete(f.base, k)
+		}
+	}
+	f.content.delete(name)
+}
+
+// Reset drops all cached digests.
+func (f *FS) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.hash = make(map[string]string)
+	f.base = make(map[string]string)
+	f.name = make(map[string]string)
+	f.sum = make(map[string][]byte)
+	f.variants = make(map[string]map[string][]byte)
+	f.stats = make(map[string]FileStat)
+	f.mtime = make(map[string]time.Time)
+	if f.index != nil {
+		f.index = make(map[string][]string)
+	}
+	f.content.reset()
+}
+
+// makeHash returns the encoded and raw digests for the given file name.
+// Concurrent computations for the same file are deduplicated.
+func (f *FS) makeHash(ctx context.Context, name string) (string, []byte, error) {
+	return f.flight.do(ctx, name, func() (string, []byte, error) {
+		return f.computeHash(ctx, name)
+	})
+}
+
+// computeHash reads the given file and computes its digests.
+func (f *FS) computeHash(ctx context.Context, name string) (string, []byte, error) {
+	start := time.Now()
+	if f.maxSize > 0 {
+		info, err := fs.Stat(f.fs, name)
+		if err != nil {
+			return "", nil, err
+		}
+		if info.Size() > f.maxSize {
+			return "", nil, &fs.PathError{Op: "hash", Path: name, Err: ErrTooLarge}
+		}
+	}
+	if f.modcheck || f.isPolling() {
+		err := f.setModTime(name)
+		if err != nil {
+			return "", nil, err
+		}
+	}
+	stored, ok := f.xattrDigest(name)
+	if ok {
+		return stored, nil, nil
+	}
+	var hash string
+	var sum []byte
+	var size int64
+	if f.impl != nil {
+		b, err := f.readFile(ctx, name)
+		if err != nil {
+			return "", nil, err
+		}
+		hash = f.impl.Sum(f.textContent(name, b))
+		size = int64(len(b))
+	} else {
+		h := f.newHash()
+		var n countWriter
+		var w io.Writer = h
+		lf := &lfWriter{w: h}
+		if f.normalizesText(name) {
+			w = lf
+		}
+		err := f.copyFile(ctx, name, io.MultiWriter(w, &n))
+		if err != nil {
+			return "", nil, err
+		}
+		err = lf.flush()
+		if err != nil {
+			return "", nil, err
+		}
+		sum = h.Sum(nil)
+		hash = f.encode(sum)
+		size = int64(n)
+	}
+	d := time.Since(start)
+	f.setStat(FileStat{Name: name, Size: size, Digest: hash, Duration: d})
+	if f.obs != nil {
+		f.obs.HashComputed(name, d)
+	}
+	if f.log != nil {
+		f.log.hashComputed(name, size, d)
+	}
+	return hash, sum, nil
+}
+
+// digest streams the given file through h and returns the raw digest.
+func (f *FS) digest(ctx context.Context, name string, h hash.Hash) ([]byte, error) {
+	err := f.copyFile(ctx, name, h)
+	if err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// readFile returns the contents of the given file.
+func (f *FS) readFile(ctx context.Context, name string) ([]byte, error) {
+	var buf bytes.Buffer
+	err := f.copyFile(ctx, name, &buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// copyFile streams the given file to w, keeping the
+// contents in the content cache if configured.
+func (f *FS) copyFile(ctx context.Context, name string, w io.Writer) error {
+	file, err := f.fs.Open(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	var buf *bytes.Buffer
+	info, err := file.Stat()
+	if err == nil && f.content.fits(info) {
+		buf = new(bytes.Buffer)
+		buf.Grow(int(info.Size()))
+		w = io.MultiWriter(w, buf)
 	}
-	digest := sha256.Sum256(b)
-	return hex.EncodeToString(digest[:])
+	_, err = io.Copy(w, contextReader{ctx: ctx, r: file})
+	if err != nil {
+		return err
+	}
+	if buf != nil {
+		f.content.add(name, buf.Bytes(), info)
+	}
+	return nil
+}
+
+// contextReader is an io.Reader that stops reading once the context is done.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+// Read implements the io.Reader interface.
+func (r contextReader) Read(p []byte) (int, error) {
+	err := r.ctx.Err()
+	if err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+// encode returns the digest encoded for use in file names.
+func (f *FS) encode(digest []byte) string {
+	s := f.enc.encode(digest)
+	if f.length > 0 {
+		s = s[:f.length]
+	}
+	return s
 }
 
 // Name returns the hashed file name for the given file.
+//
+// In the Suffix format the digest is placed before the final extension
+// only, so compound extensions such as "app.min.js", "types.d.ts" and
+// "archive.tar.gz" become "app.min.<hash>.js", "types.d.<hash>.ts" and
+// "archive.tar.<hash>.gz" respectively. See WithExtSegments.
+//
+// The URL prefix set by WithURLPrefix is prepended to the hashed name.
+// Any query or fragment, such as in "app.js?v=1", is kept. Names that
+// are already hashed, such as those returned by Name, are returned
+// unchanged rather than hashed again. Returns an empty string if the
+// file could not be hashed, or panics per WithErrorMode.
 func (f *FS) Name(name string) string {
-	ext := filepath.Ext(name)
-	hash := f.Hash(name)
+	hashed := f.nameOrEmpty(name)
+	if hashed == "" {
+		f.fail(name)
+	}
+	return hashed
+}
+
+// nameOrEmpty is like Name but never panics.
+func (f *FS) nameOrEmpty(name string) string {
+	name, suffix := splitQuery(name)
+	if !f.hashable(name) {
+		return f.prefix + name + suffix
+	}
+	if !f.modcheck {
+		f.mu.RLock()
+		hashed, ok := f.name[name]
+		f.mu.RUnlock()
+		if ok {
+			if f.obs != nil {
+				f.obs.CacheHit(name)
+			}
+			return hashed + suffix
+		}
+	}
+	hashed := f.hashedName(name)
+	if hashed == "" {
+		return f.rehashedName(name, suffix)
+	}
+	return f.prefix + hashed + suffix
+}
+
+// rehashedName returns the given name unchanged, apart from the URL
+// prefix, if it is already a valid hashed file name, such as a name
+// returned by Name. Returns an empty string otherwise.
+func (f *FS) rehashedName(name, suffix string) string {
+	_, err := f.resolve(name)
+	if err != nil {
+		return ""
+	}
+	hashed, _ := normalize(f.trimPrefix(name))
+	return f.prefix + hashed + suffix
+}
+
+// hashedName is like Name but without the URL prefix.
+func (f *FS) hashedName(name string) string {
+	if !f.hashable(name) {
+		return name
+	}
+	hash, _ := f.HashErr(name)
 	if hash == "" {
 		return ""
 	}
-	return name[:len(name)-len(ext)] + "." + hash + ext
+	return f.format(name, hash)
 }
 
-// Open implements the fs.FS interface.
-func (f *FS) Open(name string) (fs.File, error) {
-	base, ok := f.getBase(name)
-	if ok {
-		return f.fs.Open(base)
+// Names returns the hashed file names for the given files in order.
+// The names of files that fail to hash are empty.
+func (f *FS) Names(names []string) []string {
+	hashed := make([]string, len(names))
+	if f.modcheck {
+		for i, name := range names {
+			hashed[i] = f.Name(name)
+		}
+		return hashed
+	}
+	f.mu.RLock()
+	for i, name := range names {
+		hashed[i] = f.name[name]
+	}
+	f.mu.RUnlock()
+	for i, name := range names {
+		if hashed[i] == "" {
+			hashed[i] = f.Name(name)
+		}
+	}
+	return hashed
+}
+
+// NameOrDefault is like Name but returns the original
+// file name, with any URL prefix, if hashing fails.
+func (f *FS) NameOrDefault(name string) string {
+	hashed := f.nameOrEmpty(name)
+	if hashed == "" {
+		return f.prefix + name
+	}
+	return hashed
+}
+
+// NameFromDigest returns the hashed file name for the given file and
+// encoded digest without reading the file, truncating the digest to the
+// configured hash length. Unlike Name, the digest is not cached.
+func (f *FS) NameFromDigest(name, digest string) string {
+	name, suffix := splitQuery(name)
+	if f.length > 0 && !f.adaptive && len(digest) > f.length {
+		digest = digest[:f.length]
+	}
+	return f.prefix + f.format(name, digest) + suffix
+}
+
+// Base returns the original file name for the given hashed file name,
+// validating the digest if not already resolved. The bool reports
+// whether resolution succeeded.
+func (f *FS) Base(name string) (string, bool) {
+	base, err := f.resolve(name)
+	return base, err == nil
+}
+
+// format returns the hashed file name for the given file and digest.
+func (f *FS) format(name, hash string) string {
+	if f.tmpl != "" {
+		return f.formatTemplate(name, hash)
 	}
-	ext := filepath.Ext(name)
-	if ext == "" {
-		// Needs at least one extension to be a request for a hashed file.
-		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	if f.layout == PrefixDir {
+		return hash + "/" + name
 	}
-	hashExt := filepath.Ext(name[:len(name)-len(ext)])
-	if hashExt == "" {
+	ext := f.ext(name)
+	return name[:len(name)-len(ext)] + f.sep + hash + f.sizeSuffix(name) + ext
+}
+
+// parse returns the base file name and candidate digest
+// for the given hashed file name.
+func (f *FS) parse(name string) (string, string, bool) {
+	if f.tmpl != "" {
+		return f.parseTemplate(name)
+	}
+	if f.layout == PrefixDir {
+		i := strings.IndexByte(name, '/')
+		if i <= 0 {
+			// Needs a leading path segment to be a request for a hashed file.
+			return "", "", false
+		}
+		return name[i+1:], name[:i], true
+	}
+	i := strings.LastIndexByte(name, '/') + 1
+	dir, file := name[:i], name[i:]
+	if f.sizeInName {
+		trimmed, ok := f.trimSize(file)
+		if ok {
+			file = trimmed
+		}
+	}
+	ext := f.ext(file)
+	stem := file[:len(file)-len(ext)]
+	i = strings.LastIndex(stem, f.sep)
+	for n := f.extSegments - 1; i < 0 && n > 0; n-- {
+		// Maybe the file has fewer extension segments.
+		ext = extSegments(file, n)
+		stem = file[:len(file)-len(ext)]
+		i = strings.LastIndex(stem, f.sep)
+	}
+	if i < 0 {
 		// Maybe the only "extension" is the hash itself.
-		base = name[:len(name)-len(ext)]
-		hashExt = ext
+		i = strings.LastIndex(file, f.sep)
+		if i < 0 {
+			// Needs a separator to be a request for a hashed file.
+			return "", "", false
+		}
+		return dir + file[:i], file[i+len(f.sep):], true
+	}
+	return dir + stem[:i] + ext, stem[i+len(f.sep):], true
+}
+
+// Open implements the fs.FS interface. Hashed file names are validated
+// against the file contents and the returned fs.File reports the hashed
+// file name in Stat. Names of the underlying file system that are not
+// hashed, including directories, are opened unchanged, though opened
+// directories list the same entries as ReadDir. Names such as URL paths
+// are cleaned and may have a leading slash, query or fragment, but must
+// not escape the root, so fstest.TestFS reports those names as opening
+// unexpectedly.
+func (f *FS) Open(name string) (fs.File, error) {
+	file, _, err := f.OpenResolve(name)
+	return file, err
+}
+
+// OpenResolve is like Open but also returns the
+// resolved file name of the underlying file system.
+func (f *FS) OpenResolve(name string) (fs.File, string, error) {
+	base, err := f.lookup(name)
+	if err != nil {
+		if f.log != nil && errors.Is(err, ErrHashMismatch) {
+			f.log.hashMismatch(name)
+		}
+		return nil, "", &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	var file fs.File
+	c, ok := f.content.get(base)
+	if ok {
+		file = newMemFile(c)
 	} else {
-		base = name[:len(name)-len(hashExt)-len(ext)] + ext
+		file, err = f.fs.Open(base)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+	plain, _ := normalize(f.trimPrefix(name))
+	if plain == base {
+		if _, ok := file.(fs.ReadDirFile); ok {
+			info, err := file.Stat()
+			if err == nil && info.IsDir() {
+				return &dirFile{File: file, fsys: f, name: base}, base, nil
+			}
+		}
+		return file, base, nil
+	}
+	return hashedFile{File: file, name: path.Base(plain), cacheControl: f.cacheControl(base)}, base, nil
+}
+
+// ReadFile implements the fs.ReadFileFS interface.
+func (f *FS) ReadFile(name string) ([]byte, error) {
+	base, err := f.lookup(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readfile", Path: name, Err: err}
+	}
+	c, ok := f.content.get(base)
+	if ok {
+		return append([]byte(nil), c.data...), nil
+	}
+	return fs.ReadFile(f.fs, base)
+}
+
+// Sub implements the fs.SubFS interface. The returned fs.FS is
+// a *FS with the same configuration and its own caches.
+func (f *FS) Sub(dir string) (fs.FS, error) {
+	sub, err := fs.Sub(f.fs, dir)
+	if err != nil {
+		return nil, err
+	}
+	g := New(sub, f.opts...)
+	g.fs = sub // already includes any overlay
+	if g.cache != nil {
+		g.cache = subCache{dir: dir, cache: g.cache}
+	}
+	return g, nil
+}
+
+// Clone returns a new FS with the same configuration and underlying
+// file system and a copy of the cached digests. Subsequent changes to
+// the caches of either FS do not affect the other.
+func (f *FS) Clone() *FS {
+	g := New(f.fs, f.opts...)
+	g.fs = f.fs // already includes any overlay
+	g.dir = f.dir
+	g.cache = f.cache
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	for k, v := range f.hash {
+		g.hash[k] = v
 	}
-	hash := f.makeHash(base)
-	if hash == "" || hashExt[1:] != hash {
-		// Needs to exist and have valid hash.
-		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	for k, v := range f.base {
+		g.base[k] = v
+	}
+	for k, v := range f.name {
+		g.name[k] = v
+	}
+	for k, v := range f.sum {
+		g.sum[k] = v
+	}
+	for k, v := range f.variants {
+		m := make(map[string][]byte, len(v))
+		for enc, b := range v {
+			m[enc] = b
+		}
+		g.variants[k] = m
+	}
+	for k, v := range f.stats {
+		g.stats[k] = v
+	}
+	for k, v := range f.mtime {
+		g.mtime[k] = v
+	}
+	if g.index != nil {
+		for k, v := range f.index {
+			g.index[k] = append([]string(nil), v...)
+		}
+	}
+	return g
+}
+
+// Stat implements the fs.StatFS interface. The returned
+// fs.FileInfo reports the hashed file name and, for hashed
+// names, implements CacheControlInfo.
+func (f *FS) Stat(name string) (fs.FileInfo, error) {
+	base, err := f.lookup(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
 	}
+	info, err := fs.Stat(f.fs, base)
+	if err != nil {
+		return nil, err
+	}
+	plain, _ := normalize(f.trimPrefix(name))
+	fi := fileInfo{FileInfo: info, name: path.Base(plain)}
+	if plain == base {
+		return fi, nil
+	}
+	return cacheInfo{fileInfo: fi, cacheControl: f.cacheControl(base)}, nil
+}
+
+// ReadDir implements the fs.ReadDirFS interface. Regular file entries
+// report their hashed file names, sorted by those names. Entries for
+// files that fail to hash are returned with their base names.
+func (f *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries, err := fs.ReadDir(f.fs, name)
+	if err != nil {
+		return nil, err
+	}
+	for i, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		hashed := f.hashedName(path.Join(name, entry.Name()))
+		if hashed == "" {
+			continue
+		}
+		entries[i] = dirEntry{DirEntry: entry, name: path.Base(hashed)}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, nil
+}
+
+// Glob implements the fs.GlobFS interface. The pattern follows path.Match
+// semantics and is matched against the hashed names of regular files.
+func (f *FS) Glob(pattern string) ([]string, error) {
+	_, err := path.Match(pattern, "")
+	if err != nil {
+		return nil, err
+	}
+	depth := strings.Count(pattern, "/")
+	var matches []string
+	err = fs.WalkDir(f.fs, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if name == "." {
+			return nil
+		}
+		if d.Type().IsRegular() {
+			hashed := f.hashedName(name)
+			if hashed != "" {
+				name = hashed
+			}
+		}
+		ok, _ := path.Match(pattern, name)
+		if ok {
+			matches = append(matches, name)
+		}
+		if d.IsDir() && strings.Count(name, "/") >= depth {
+			return fs.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// dirEntry is a fs.DirEntry that reports the hashed file name.
+type dirEntry struct {
+	fs.DirEntry
+	name string
+}
+
+// Name implements the fs.DirEntry interface.
+func (d dirEntry) Name() string {
+	return d.name
+}
+
+// Info implements the fs.DirEntry interface.
+func (d dirEntry) Info() (fs.FileInfo, error) {
+	info, err := d.DirEntry.Info()
+	if err != nil {
+		return nil, err
+	}
+	return fileInfo{FileInfo: info, name: d.name}, nil
+}
+
+// dirFile is a fs.ReadDirFile that lists the same entries as ReadDir.
+type dirFile struct {
+	fs.File
+	fsys    *FS
+	name    string
+	entries []fs.DirEntry
+	read    bool
+}
+
+// ReadDir implements the fs.ReadDirFile interface.
+func (d *dirFile) ReadDir(count int) ([]fs.DirEntry, error) {
+	if !d.read {
+		entries, err := d.fsys.ReadDir(d.name)
+		if err != nil {
+			return nil, err
+		}
+		d.entries = entries
+		d.read = true
+	}
+	n := len(d.entries)
+	if count > 0 && count < n {
+		n = count
+	}
+	if count > 0 && n == 0 {
+		return nil, io.EOF
+	}
+	entries := d.entries[:n]
+	d.entries = d.entries[n:]
+	return entries, nil
+}
+
+// fileInfo is a fs.FileInfo that reports the hashed file name.
+type fileInfo struct {
+	fs.FileInfo
+	name string
+}
+
+// Name implements the fs.FileInfo interface.
+func (fi fileInfo) Name() string {
+	return fi.name
+}
+
+// hashedFile is a fs.File that reports the hashed file name
+// and its Cache-Control value.
+type hashedFile struct {
+	fs.File
+	name         string
+	cacheControl string
+}
+
+// Stat implements the fs.File interface.
+func (f hashedFile) Stat() (fs.FileInfo, error) {
+	info, err := f.File.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return cacheInfo{fileInfo: fileInfo{FileInfo: info, name: f.name}, cacheControl: f.cacheControl}, nil
+}
+
+// Seek implements the io.Seeker interface
+// if the underlying file does.
+func (f hashedFile) Seek(offset int64, whence int) (int64, error) {
+	s, ok := f.File.(io.Seeker)
+	if !ok {
+		return 0, &fs.PathError{Op: "seek", Path: f.name, Err: fs.ErrInvalid}
+	}
+	return s.Seek(offset, whence)
+}
+
+// ReadAt implements the io.ReaderAt interface
+// if the underlying file does.
+func (f hashedFile) ReadAt(p []byte, off int64) (int, error) {
+	r, ok := f.File.(io.ReaderAt)
+	if !ok {
+		return 0, &fs.PathError{Op: "read", Path: f.name, Err: fs.ErrInvalid}
+	}
+	return r.ReadAt(p, off)
+}
+
+// resolve returns the base file name for the given hashed file name.
+// Returns fs.ErrNotExist if the file does not exist or ErrHashMismatch
+// if the digest does not match the file contents.
+func (f *FS) resolve(name string) (string, error) {
+	name, ok := normalize(f.trimPrefix(name))
+	if !ok {
+		return "", fs.ErrInvalid
+	}
+	base, ok := f.getBase(name)
+	if ok && !f.verify && !f.stale(base) {
+		if f.obs != nil {
+			f.obs.CacheHit(name)
+		}
+		return base, nil
+	}
+	base, ok = f.getIndex(name)
+	if ok && !f.stale(base) {
+		return base, nil
+	}
+	base, want, ok := f.parse(name)
+	if !ok || !f.hashable(base) {
+		return "", fs.ErrNotExist
+	}
+	if f.impl != nil {
+		return f.resolveImpl(name, base, want)
+	}
+	hash, sum, err := f.makeHash(context.Background(), base)
+	if err != nil {
+		// Needs to exist.
+		var e *fs.PathError
+		if errors.As(err, &e) {
+			return "", e.Err
+		}
+		return "", err
+	}
+	if f.fold && f.enc == Hex {
+		want = strings.ToLower(want)
+	}
+	cached, ok := f.getHash(base)
+	// Verified files may have changed since they were hashed.
+	changed := ok && f.verify && !f.equal(cached, hash) && !f.matchLonger(cached, sum)
+	if f.equal(want, hash) || f.matchLonger(want, sum) {
+		if !ok || changed {
+			// Longer digests in requests only resolve, keeping
+			// Name to the length chosen by the FS itself.
+			err = f.setHash(base, hash, sum)
+			if err != nil {
+				return "", err
+			}
+		}
+		f.setBase(name, base)
+		return base, nil
+	}
+	if !ok || changed {
+		// Spare Name from reading the file again
+		// and from naming a changed file by its old digest.
+		f.setHash(base, hash, sum)
+	}
+	for _, fn := range f.alt {
+		sum, err := f.textDigest(context.Background(), base, fn())
+		if err == nil && f.equal(want, f.encode(sum)) {
+			f.setBase(name, base)
+			return base, nil
+		}
+	}
+	// Needs to have valid hash.
+	return "", ErrHashMismatch
+}
+
+// lookup is like resolve but also resolves the plain file names
+// of the underlying file system to themselves unless strict and
+// hashable. Plain names that only look hashed, such as app.min.js
+// beside app.js, are resolved to themselves despite the mismatch.
+func (f *FS) lookup(name string) (string, error) {
+	base, err := f.resolve(name)
+	if !errors.Is(err, fs.ErrNotExist) && !errors.Is(err, ErrHashMismatch) {
+		return base, err
+	}
+	name, _ = normalize(f.trimPrefix(name))
+	if f.strict && f.hashable(name) {
+		return "", err
+	}
+	_, serr := fs.Stat(f.fs, name)
+	if serr != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// splitQuery splits the given name, such as a URL path, before
+// any query or fragment.
+func splitQuery(name string) (string, string) {
+	i := strings.IndexAny(name, "?#")
+	if i < 0 {
+		return name, ""
+	}
+	return name[:i], name[i:]
+}
+
+// normalize returns the given name, such as a URL path, as a valid
+// fs.FS path without a leading slash, query or fragment. Reports false
+// if the name escapes the root.
+func normalize(name string) (string, bool) {
+	name, _ = splitQuery(name)
+	name = path.Clean(strings.TrimLeft(name, "/"))
+	if name == ".." || strings.HasPrefix(name, "../") {
+		return "", false
+	}
+	return name, true
+}
+
+// trimPrefix returns name without the URL prefix, if any.
+// Leading slashes are not significant.
+func (f *FS) trimPrefix(name string) string {
+	if f.prefix == "" {
+		return name
+	}
+	prefix := strings.TrimPrefix(f.prefix, "/")
+	trimmed := strings.TrimPrefix(name, "/")
+	if !strings.HasPrefix(trimmed, prefix) {
+		return name
+	}
+	return trimmed[len(prefix):]
+}
+
+// equal reports whether the digests are equal,
+// in constant time if configured.
+func (f *FS) equal(a, b string) bool {
+	if f.constant {
+		return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+	}
+	return a == b
+}
+
+// setBase performs a synchronized update on the base map
+// and the shared cache, if any.
+func (f *FS) setBase(name, base string) {
 	f.mu.Lock()
-	f.hash[base] = hash
 	f.base[name] = base
 	f.mu.Unlock()
-	return f.fs.Open(base)
+	if f.cache != nil {
+		f.cache.SetBase(name, base)
+	}
 }
 
-// getBase performs a synchronized lookup on the base map.
+// getBase performs a synchronized lookup on the base map,
+// falling back to the shared cache, if any.
 func (f *FS) getBase(name string) (string, bool) {
 	f.mu.RLock()
-	defer f.mu.RUnlock()
 	base, ok := f.base[name]
+	f.mu.RUnlock()
+	if ok || f.cache == nil {
+		return base, ok
+	}
+	base, ok = f.cache.GetBase(name)
+	if ok {
+		f.mu.Lock()
+		f.base[name] = base
+		f.mu.Unlock()
+	}
 	return base, ok
 }
@@ -1,14 +1,36 @@
 package hashfs
 
 import (
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
 	"embed"
+	"encoding/hex"
+	"errors"
+	"io"
 	"io/fs"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
+	"testing/fstest"
+	"testing/iotest"
 )
 
 //go:embed testdata
 var testdata embed.FS
 
+func TestNewNil(t *testing.T) {
+	defer func() {
+		r := recover()
+		if r != "hashfs: nil fs.FS" {
+			t.Errorf("New(nil) should panic\nhave %v\nwant %q", r, "hashfs: nil fs.FS")
+		}
+	}()
+	New(nil)
+}
+
 func TestHash(t *testing.T) {
 	tests := []struct {
 		name string
@@ -40,6 +62,163 @@ func TestHash(t *testing.T) {
 	}
 }
 
+func TestNameOrDefault(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"testdata/base.ext", "testdata/base.d476fb7be1b02ea9f66c797a0c11b11ff5db1bd702ff6c4720e455a301a501f1.ext"},
+		{"testdata/missing.ext", "testdata/missing.ext"},
+	}
+	h := New(testdata)
+	for _, tt := range tests {
+		have := h.NameOrDefault(tt.name)
+		if have != tt.want {
+			t.Errorf("NameOrDefault(%q)\nhave '%s'\nwant '%s'", tt.name, have, tt.want)
+		}
+	}
+}
+
+func TestNameIdempotent(t *testing.T) {
+	tests := []struct {
+		opts []Option
+		name string
+		want string
+	}{
+		{nil, "testdata/base.ext", "testdata/base.d476fb7b.ext"},
+		{[]Option{WithURLPrefix("/static/")}, "testdata/base.ext", "/static/testdata/base.d476fb7b.ext"},
+		{[]Option{WithFormat(PrefixDir)}, "testdata/noext", "d9d4e730/testdata/noext"},
+	}
+	for i, tt := range tests {
+		h := New(testdata, append([]Option{WithHashLength(8)}, tt.opts...)...)
+		once := h.Name(tt.name)
+		twice := h.Name(once)
+		if once != tt.want || twice != tt.want {
+			t.Errorf("%d. Name(Name(%q))\nhave '%s', '%s'\nwant '%s'", i, tt.name, once, twice, tt.want)
+		}
+		twice = New(testdata, append([]Option{WithHashLength(8)}, tt.opts...)...).Name(once)
+		if twice != tt.want {
+			t.Errorf("%d. Name(%q) on a new FS\nhave '%s'\nwant '%s'", i, once, twice, tt.want)
+		}
+	}
+	h := New(testdata, WithHashLength(8))
+	if h.Name("testdata/base.88888888.ext") != "" {
+		t.Errorf("Name should not accept invalid digests")
+	}
+}
+
+func TestNameFromDigest(t *testing.T) {
+	const digest = "d476fb7be1b02ea9f66c797a0c11b11ff5db1bd702ff6c4720e455a301a501f1"
+	tests := []struct {
+		opts []Option
+		name string
+		want string
+	}{
+		{nil, "testdata/base.ext", "testdata/base." + digest + ".ext"},
+		{[]Option{WithHashLength(8)}, "testdata/base.ext", "testdata/base.d476fb7b.ext"},
+		{[]Option{WithURLPrefix("/static/")}, "missing.ext?v=1", "/static/missing." + digest + ".ext?v=1"},
+	}
+	for i, tt := range tests {
+		h := New(fstest.MapFS{}, tt.opts...)
+		have := h.NameFromDigest(tt.name, digest)
+		if have != tt.want {
+			t.Errorf("%d. NameFromDigest(%q)\nhave '%s'\nwant '%s'", i, tt.name, have, tt.want)
+		}
+	}
+}
+
+func TestNameAllocs(t *testing.T) {
+	h := New(testdata)
+	h.Name("testdata/base.ext")
+	allocs := testing.AllocsPerRun(100, func() {
+		h.Name("testdata/base.ext")
+	})
+	if allocs != 0 {
+		t.Errorf("Name should not allocate for cached files, have %v allocs", allocs)
+	}
+}
+
+func BenchmarkName(b *testing.B) {
+	h := New(testdata)
+	h.Name("testdata/base.ext")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h.Name("testdata/base.ext")
+	}
+}
+
+func TestNameQuery(t *testing.T) {
+	h := New(testdata)
+	const hashed = "testdata/base.d476fb7be1b02ea9f66c797a0c11b11ff5db1bd702ff6c4720e455a301a501f1.ext"
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"testdata/base.ext?v=1", hashed + "?v=1"},
+		{"testdata/base.ext#top", hashed + "#top"},
+		{"testdata/base.ext?v=1#top", hashed + "?v=1#top"},
+	}
+	for _, tt := range tests {
+		have := h.Name(tt.name)
+		if have != tt.want {
+			t.Errorf("Name(%q)\nhave '%s'\nwant '%s'", tt.name, have, tt.want)
+		}
+		_, err := h.ReadFile(have)
+		if err != nil {
+			t.Errorf("ReadFile(%q) unexpected error: %v", have, err)
+		}
+	}
+}
+
+func TestNames(t *testing.T) {
+	h := New(testdata)
+	h.Hash("testdata/noext")
+	names := []string{"testdata/base.ext", "testdata/missing.ext", "testdata/noext"}
+	want := []string{
+		"testdata/base.d476fb7be1b02ea9f66c797a0c11b11ff5db1bd702ff6c4720e455a301a501f1.ext",
+		"",
+		"testdata/noext.d9d4e730296e72377ae86529027f7defd5feecf4602a1f15f561cd4fae3644c5",
+	}
+	have := h.Names(names)
+	if len(have) != len(want) {
+		t.Fatalf("len\nhave %d\nwant %d", len(have), len(want))
+	}
+	for i := range want {
+		if have[i] != want[i] {
+			t.Errorf("Names()[%d]\nhave '%s'\nwant '%s'", i, have[i], want[i])
+		}
+	}
+}
+
+func TestURLPrefix(t *testing.T) {
+	h := New(testdata, WithURLPrefix("/static/"))
+	const name = "testdata/base.d476fb7be1b02ea9f66c797a0c11b11ff5db1bd702ff6c4720e455a301a501f1.ext"
+	have := h.Name("testdata/base.ext")
+	if have != "/static/"+name {
+		t.Fatalf("Name\nhave '%s'\nwant '%s'", have, "/static/"+name)
+	}
+	have = h.NameOrDefault("testdata/missing.ext")
+	if have != "/static/testdata/missing.ext" {
+		t.Errorf("NameOrDefault\nhave '%s'\nwant '%s'", have, "/static/testdata/missing.ext")
+	}
+	tests := []string{name, "static/" + name, "/static/" + name}
+	for _, tt := range tests {
+		b, err := h.ReadFile(tt)
+		if err != nil {
+			t.Errorf("ReadFile(%q) unexpected error: %v", tt, err)
+			continue
+		}
+		if string(b) != "base.ext\n" {
+			t.Errorf("ReadFile(%q)\nhave %q\nwant %q", tt, b, "base.ext\n")
+		}
+	}
+	m := h.Manifest()
+	if m["testdata/base.ext"] != name {
+		t.Errorf("Manifest\nhave '%s'\nwant '%s'", m["testdata/base.ext"], name)
+	}
+}
+
 func TestHashPathError(t *testing.T) {
 	h := New(testdata)
 	hash := h.Hash("not-found")
@@ -48,6 +227,192 @@ func TestHashPathError(t *testing.T) {
 	}
 }
 
+func TestPathErrorOp(t *testing.T) {
+	h := New(testdata)
+	const name = "testdata/base.8888888888888888888888888888888888888888888888888888888888888888.ext"
+	tests := []struct {
+		op string
+		fn func() error
+	}{
+		{"hash", func() error { _, err := h.HashErr("not-found"); return err }},
+		{"rawhash", func() error { _, err := h.RawHash("not-found"); return err }},
+		{"open", func() error { _, err := h.Open(name); return err }},
+		{"readfile", func() error { _, err := h.ReadFile(name); return err }},
+		{"stat", func() error { _, err := h.Stat(name); return err }},
+	}
+	for _, tt := range tests {
+		var e *fs.PathError
+		err := tt.fn()
+		if !errors.As(err, &e) {
+			t.Errorf("%s should yield a *fs.PathError, have %v", tt.op, err)
+			continue
+		}
+		if e.Op != tt.op {
+			t.Errorf("Op\nhave '%s'\nwant '%s'", e.Op, tt.op)
+		}
+	}
+}
+
+func TestHashErr(t *testing.T) {
+	h := New(testdata)
+	hash, err := h.HashErr("testdata/base.ext")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "d476fb7be1b02ea9f66c797a0c11b11ff5db1bd702ff6c4720e455a301a501f1"
+	if hash != want {
+		t.Errorf("HashErr\nhave '%s'\nwant '%s'", hash, want)
+	}
+	_, err = h.HashErr("not-found")
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("HashErr should yield fs.ErrNotExist, have %v", err)
+	}
+	if _, ok := err.(*fs.PathError); !ok {
+		t.Errorf("HashErr should yield a *fs.PathError")
+	}
+}
+
+func TestHashContext(t *testing.T) {
+	h := New(testdata)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := h.HashContext(ctx, "testdata/base.ext")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("HashContext should yield context.Canceled, have %v", err)
+	}
+	if _, ok := h.getHash("testdata/base.ext"); ok {
+		t.Errorf("HashContext should not cache canceled digests")
+	}
+	hash, err := h.HashContext(context.Background(), "testdata/base.ext")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "d476fb7be1b02ea9f66c797a0c11b11ff5db1bd702ff6c4720e455a301a501f1"
+	if hash != want {
+		t.Errorf("HashContext\nhave '%s'\nwant '%s'", hash, want)
+	}
+}
+
+func TestRawHash(t *testing.T) {
+	h := New(testdata, WithHashLength(8))
+	sum, err := h.RawHash("testdata/base.ext")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "d476fb7be1b02ea9f66c797a0c11b11ff5db1bd702ff6c4720e455a301a501f1"
+	if hex.EncodeToString(sum) != want {
+		t.Errorf("RawHash\nhave '%x'\nwant '%s'", sum, want)
+	}
+	sum[0] = 0
+	sum, _ = h.RawHash("testdata/base.ext")
+	if hex.EncodeToString(sum) != want {
+		t.Errorf("RawHash should return a copy")
+	}
+	_, err = h.RawHash("not-found")
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("RawHash should yield fs.ErrNotExist, have %v", err)
+	}
+}
+
+func TestShortID(t *testing.T) {
+	tests := []struct {
+		h    *FS
+		name string
+		want string
+	}{
+		{New(testdata), "testdata/base.ext", "d476fb7b"},
+		{New(testdata, WithHashLength(4), WithEncoding(Base64URL)), "testdata/base.ext", "d476fb7b"},
+		{New(testdata), "not-found", ""},
+	}
+	for i, tt := range tests {
+		have := tt.h.ShortID(tt.name)
+		if have != tt.want {
+			t.Errorf("%d. ShortID(%q)\nhave '%s'\nwant '%s'", i, tt.name, have, tt.want)
+		}
+	}
+}
+
+func TestHashWithHasher(t *testing.T) {
+	h := New(testdata, WithHasher(sha512.New))
+	name := "testdata/base.ext"
+	want := "testdata/base.be05eecfcebe6adda9345d880d76cb1150971e698931b820bf53f9f88081a3d0498db1a0962dca6a70ce5d137f906de84f172e1e678bdf1bf39be98673fc15c7.ext"
+	have := h.Name(name)
+	if have != want {
+		t.Fatalf("Name(%q)\nhave '%s'\nwant '%s'", name, have, want)
+	}
+	f, err := New(testdata, WithHasher(sha512.New)).Open(have)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = f.Close()
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestHashWithEncoding(t *testing.T) {
+	h := New(testdata, WithEncoding(Base64URL))
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"testdata/base.ext", "testdata/base.1Hb7e-GwLqn2bHl6DBGxH_XbG9cC_2xHIORVowGlAfE.ext"},
+		{"testdata/noext", "testdata/noext.2dTnMClucjd66GUpAn9979X-7PRgKh8V9WHNT642RMU"},
+	}
+	for _, tt := range tests {
+		have := h.Name(tt.name)
+		if have != tt.want {
+			t.Errorf("Name(%q)\nhave '%s'\nwant '%s'", tt.name, have, tt.want)
+			continue
+		}
+		f, err := New(testdata, WithEncoding(Base64URL)).Open(have)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			continue
+		}
+		err = f.Close()
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+}
+
+func TestHashWithHashLength(t *testing.T) {
+	h := New(testdata, WithHashLength(8))
+	name := "testdata/base.ext"
+	want := "testdata/base.d476fb7b.ext"
+	have := h.Name(name)
+	if have != want {
+		t.Fatalf("Name(%q)\nhave '%s'\nwant '%s'", name, have, want)
+	}
+	f, err := New(testdata, WithHashLength(8)).Open(have)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = f.Close()
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	_, err = h.Open("testdata/base.d476fb7be1b02ea9f66c797a0c11b11ff5db1bd702ff6c4720e455a301a501f1.ext")
+	if err == nil {
+		t.Errorf("Open should error on full digest")
+	}
+}
+
+func TestHashWithHashLengthInvalid(t *testing.T) {
+	tests := []int{-1, 0, 65}
+	for _, tt := range tests {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("WithHashLength(%d) should panic", tt)
+				}
+			}()
+			New(testdata, WithHashLength(tt))
+		}()
+	}
+}
+
 func TestOpen(t *testing.T) {
 	h := New(testdata)
 	tests := []string{
@@ -67,12 +432,109 @@ func TestOpen(t *testing.T) {
 	}
 }
 
+func TestURLPrefixSegment(t *testing.T) {
+	m := fstest.MapFS{
+		"app.js":       &fstest.MapFile{Data: []byte("a")},
+		"files/app.js": &fstest.MapFile{Data: []byte("b")},
+	}
+	h := New(m, WithURLPrefix("/static"), WithHashLength(8))
+	have := h.Name("app.js")
+	if have != "/static/app.ca978112.js" {
+		t.Errorf("Name\nhave '%s'\nwant '%s'", have, "/static/app.ca978112.js")
+	}
+	_, err := h.Open("/staticfiles/app.js")
+	if err == nil {
+		t.Errorf("Open should not trim the prefix from a longer path segment")
+	}
+	b, err := h.ReadFile("/static/app.ca978112.js")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(b) != "a" {
+		t.Errorf("ReadFile\nhave %q\nwant %q", b, "a")
+	}
+}
+
+func TestOpenResolve(t *testing.T) {
+	h := New(testdata, WithURLPrefix("/static/"))
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"/static/testdata/base.d476fb7be1b02ea9f66c797a0c11b11ff5db1bd702ff6c4720e455a301a501f1.ext", "testdata/base.ext"},
+		{"/static/testdata/noext", "testdata/noext"},
+	}
+	for _, tt := range tests {
+		f, base, err := h.OpenResolve(tt.name)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			continue
+		}
+		f.Close()
+		if base != tt.want {
+			t.Errorf("OpenResolve(%q)\nhave '%s'\nwant '%s'", tt.name, base, tt.want)
+		}
+	}
+	_, base, err := h.OpenResolve("testdata/missing.ext")
+	if err == nil || base != "" {
+		t.Errorf("OpenResolve should error with an empty name for missing files")
+	}
+}
+
+func TestOpenStat(t *testing.T) {
+	h := New(testdata)
+	name := "testdata/base.d476fb7be1b02ea9f66c797a0c11b11ff5db1bd702ff6c4720e455a301a501f1.ext"
+	f, err := h.Open(name)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info.Name() != path.Base(name) {
+		t.Errorf("Name()\nhave '%s'\nwant '%s'", info.Name(), path.Base(name))
+	}
+	if info.Size() != 9 {
+		t.Errorf("Size()\nhave %d\nwant %d", info.Size(), 9)
+	}
+	_, ok := f.(io.Seeker)
+	if !ok {
+		t.Errorf("Open should preserve io.Seeker")
+	}
+}
+
+func TestOpenStatQuery(t *testing.T) {
+	h := New(testdata)
+	name := "/testdata/base.d476fb7be1b02ea9f66c797a0c11b11ff5db1bd702ff6c4720e455a301a501f1.ext?v=1#x"
+	want := "base.d476fb7be1b02ea9f66c797a0c11b11ff5db1bd702ff6c4720e455a301a501f1.ext"
+	f, err := h.Open(name)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info.Name() != want {
+		t.Errorf("Open Name()\nhave '%s'\nwant '%s'", info.Name(), want)
+	}
+	info, err = h.Stat(name)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info.Name() != want {
+		t.Errorf("Stat Name()\nhave '%s'\nwant '%s'", info.Name(), want)
+	}
+}
+
 func TestOpenPathError(t *testing.T) {
 	h := New(testdata)
 	tests := []string{
-		"testdata/base.ext",
+		"testdata/missing.ext",
 		"testdata/base.8888888888888888888888888888888888888888888888888888888888888888.ext",
-		"testdata/noext",
 		"testdata/noext.8888888888888888888888888888888888888888888888888888888888888888",
 	}
 	for _, tt := range tests {
@@ -87,3 +549,736 @@ func TestOpenPathError(t *testing.T) {
 		}
 	}
 }
+
+func TestReadFile(t *testing.T) {
+	h := New(testdata)
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"testdata/base.d476fb7be1b02ea9f66c797a0c11b11ff5db1bd702ff6c4720e455a301a501f1.ext", "base.ext\n"},
+		{"testdata/noext.d9d4e730296e72377ae86529027f7defd5feecf4602a1f15f561cd4fae3644c5", "noext\n"},
+	}
+	for _, tt := range tests {
+		b, err := fs.ReadFile(h, tt.name)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			continue
+		}
+		if string(b) != tt.want {
+			t.Errorf("ReadFile(%q)\nhave %q\nwant %q", tt.name, b, tt.want)
+		}
+	}
+	_, err := h.ReadFile("testdata/missing.ext")
+	if _, ok := err.(*fs.PathError); !ok {
+		t.Errorf("ReadFile should yield a *fs.PathError")
+	}
+}
+
+func TestStat(t *testing.T) {
+	h := New(testdata)
+	name := "testdata/base.d476fb7be1b02ea9f66c797a0c11b11ff5db1bd702ff6c4720e455a301a501f1.ext"
+	info, err := fs.Stat(h, name)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "base.d476fb7be1b02ea9f66c797a0c11b11ff5db1bd702ff6c4720e455a301a501f1.ext"
+	if info.Name() != want {
+		t.Errorf("Name()\nhave '%s'\nwant '%s'", info.Name(), want)
+	}
+	if info.Size() != 9 {
+		t.Errorf("Size()\nhave %d\nwant %d", info.Size(), 9)
+	}
+	_, err = h.Stat("testdata/missing.ext")
+	if _, ok := err.(*fs.PathError); !ok {
+		t.Errorf("Stat should yield a *fs.PathError")
+	}
+}
+
+func TestOpenNormalize(t *testing.T) {
+	h := New(testdata)
+	tests := []string{
+		"/testdata/base.d476fb7be1b02ea9f66c797a0c11b11ff5db1bd702ff6c4720e455a301a501f1.ext",
+		"testdata//base.d476fb7be1b02ea9f66c797a0c11b11ff5db1bd702ff6c4720e455a301a501f1.ext",
+		"testdata/./base.d476fb7be1b02ea9f66c797a0c11b11ff5db1bd702ff6c4720e455a301a501f1.ext",
+		"other/../testdata/base.d476fb7be1b02ea9f66c797a0c11b11ff5db1bd702ff6c4720e455a301a501f1.ext",
+	}
+	for _, tt := range tests {
+		b, err := h.ReadFile(tt)
+		if err != nil {
+			t.Errorf("ReadFile(%q) unexpected error: %v", tt, err)
+			continue
+		}
+		if string(b) != "base.ext\n" {
+			t.Errorf("ReadFile(%q)\nhave %q\nwant %q", tt, b, "base.ext\n")
+		}
+	}
+	_, err := h.Open("../testdata/base.d476fb7be1b02ea9f66c797a0c11b11ff5db1bd702ff6c4720e455a301a501f1.ext")
+	if !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("Open should yield fs.ErrInvalid for paths escaping the root, have %v", err)
+	}
+}
+
+func TestOpenPlain(t *testing.T) {
+	h := New(testdata)
+	b, err := fs.ReadFile(h, "testdata/base.ext")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(b) != "base.ext\n" {
+		t.Errorf("ReadFile\nhave %q\nwant %q", b, "base.ext\n")
+	}
+	info, err := fs.Stat(h, "testdata")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !info.IsDir() {
+		t.Errorf("Stat should report a directory")
+	}
+	_, ok := h.Base("testdata/base.ext")
+	if ok {
+		t.Errorf("Base should not resolve plain names")
+	}
+}
+
+func TestOpenPlainDotted(t *testing.T) {
+	m := fstest.MapFS{
+		"app.js":       &fstest.MapFile{Data: []byte("a")},
+		"app.min.js":   &fstest.MapFile{Data: []byte("b")},
+		"style.css":    &fstest.MapFile{Data: []byte("c")},
+		"style.v2.css": &fstest.MapFile{Data: []byte("d")},
+	}
+	h := New(m)
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"app.min.js", "b"},
+		{"style.v2.css", "d"},
+	}
+	for _, tt := range tests {
+		b, err := fs.ReadFile(h, tt.name)
+		if err != nil {
+			t.Errorf("ReadFile(%q) unexpected error: %v", tt.name, err)
+			continue
+		}
+		if string(b) != tt.want {
+			t.Errorf("ReadFile(%q)\nhave %q\nwant %q", tt.name, b, tt.want)
+		}
+		_, err = h.Stat(tt.name)
+		if err != nil {
+			t.Errorf("Stat(%q) unexpected error: %v", tt.name, err)
+		}
+	}
+	_, err := New(m, WithStrictHashed(true)).Open("app.min.js")
+	if !errors.Is(err, ErrHashMismatch) {
+		t.Errorf("Open should yield ErrHashMismatch when strict, have %v", err)
+	}
+}
+
+func TestOpenConstantTimeCompare(t *testing.T) {
+	h := New(testdata, WithConstantTimeCompare(true))
+	tests := []struct {
+		name string
+		ok   bool
+	}{
+		{"testdata/base.d476fb7be1b02ea9f66c797a0c11b11ff5db1bd702ff6c4720e455a301a501f1.ext", true},
+		{"testdata/base.8888888888888888888888888888888888888888888888888888888888888888.ext", false},
+		{"testdata/base.d476fb7b.ext", false},
+	}
+	for _, tt := range tests {
+		_, ok := h.Base(tt.name)
+		if ok != tt.ok {
+			t.Errorf("Base(%q)\nhave %t\nwant %t", tt.name, ok, tt.ok)
+		}
+	}
+}
+
+func TestOpenStrictHashed(t *testing.T) {
+	h := New(testdata, WithStrictHashed(true))
+	for _, name := range []string{"testdata/base.ext", "testdata"} {
+		_, err := h.Open(name)
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("Open(%q) should yield fs.ErrNotExist, have %v", name, err)
+		}
+	}
+	f, err := h.Open("testdata/base.d476fb7be1b02ea9f66c797a0c11b11ff5db1bd702ff6c4720e455a301a501f1.ext")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	f.Close()
+}
+
+func TestOpenHashMismatch(t *testing.T) {
+	h := New(testdata)
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"testdata/base.8888888888888888888888888888888888888888888888888888888888888888.ext", ErrHashMismatch},
+		{"testdata/noext.8888888888888888888888888888888888888888888888888888888888888888", ErrHashMismatch},
+		{"testdata/missing.8888888888888888888888888888888888888888888888888888888888888888.ext", fs.ErrNotExist},
+		{"testdata/missing", fs.ErrNotExist},
+	}
+	for _, tt := range tests {
+		_, err := h.Open(tt.name)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("Open(%q) should yield %v, have %v", tt.name, tt.err, err)
+		}
+	}
+}
+
+func TestOpenWithVerifyOnOpen(t *testing.T) {
+	tests := []struct {
+		verify bool
+		ok     bool
+	}{
+		{false, true},
+		{true, false},
+	}
+	for _, tt := range tests {
+		m := fstest.MapFS{"a.txt": &fstest.MapFile{Data: []byte("a")}}
+		h := New(m, WithVerifyOnOpen(tt.verify))
+		name := h.Name("a.txt")
+		m["a.txt"].Data = []byte("changed")
+		f, err := h.Open(name)
+		if tt.ok {
+			if err != nil {
+				t.Errorf("WithVerifyOnOpen(%t) unexpected error: %v", tt.verify, err)
+				continue
+			}
+			f.Close()
+			continue
+		}
+		if !errors.Is(err, ErrHashMismatch) {
+			t.Errorf("WithVerifyOnOpen(%t) should yield ErrHashMismatch, have %v", tt.verify, err)
+		}
+		if h.Name("a.txt") == name {
+			t.Errorf("WithVerifyOnOpen(%t) Name should change with the file", tt.verify)
+			continue
+		}
+		f, err = h.Open(h.Name("a.txt"))
+		if err != nil {
+			t.Errorf("WithVerifyOnOpen(%t) unexpected error: %v", tt.verify, err)
+			continue
+		}
+		f.Close()
+	}
+}
+
+func TestInvalidate(t *testing.T) {
+	m := fstest.MapFS{
+		"a.txt": &fstest.MapFile{Data: []byte("a")},
+		"b.txt": &fstest.MapFile{Data: []byte("b")},
+	}
+	h := New(m, WithContentCache(1024))
+	a := h.Name("a.txt")
+	b := h.Name("b.txt")
+	m["a.txt"].Data = []byte("changed")
+	h.Invalidate("a.txt")
+	_, err := h.Open(a)
+	if !errors.Is(err, ErrHashMismatch) {
+		t.Errorf("Open(%q) should yield ErrHashMismatch, have %v", a, err)
+	}
+	if h.Name("a.txt") == a {
+		t.Errorf("Name should recompute the digest after Invalidate")
+	}
+	if _, ok := h.getBase(b); !ok {
+		t.Errorf("Invalidate should not drop other files")
+	}
+}
+
+func TestReset(t *testing.T) {
+	m := fstest.MapFS{"a.txt": &fstest.MapFile{Data: []byte("a")}}
+	h := New(m, WithContentCache(1024))
+	a := h.Name("a.txt")
+	m["a.txt"].Data = []byte("changed")
+	h.Reset()
+	if len(h.hash) != 0 || len(h.base) != 0 || len(h.sum) != 0 || h.content.size != 0 {
+		t.Errorf("Reset should drop all cached digests")
+	}
+	_, err := h.Open(a)
+	if !errors.Is(err, ErrHashMismatch) {
+		t.Errorf("Open(%q) should yield ErrHashMismatch, have %v", a, err)
+	}
+}
+
+func TestSub(t *testing.T) {
+	h := New(testdata, WithHashLength(8))
+	sub, err := fs.Sub(h, "testdata")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s, ok := sub.(*FS)
+	if !ok {
+		t.Fatalf("Sub should return a *FS")
+	}
+	name := s.Name("base.ext")
+	want := "base.d476fb7b.ext"
+	if name != want {
+		t.Errorf("Name\nhave '%s'\nwant '%s'", name, want)
+	}
+	f, err := s.Open(name)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	f.Close()
+	if len(h.hash) != 0 {
+		t.Errorf("Sub should have its own caches")
+	}
+	_, err = h.Sub("../invalid")
+	if err == nil {
+		t.Errorf("Sub should error on invalid directory")
+	}
+}
+
+func TestClone(t *testing.T) {
+	h := New(testdata)
+	err := h.WarmCache()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	g := h.Clone()
+	if len(g.hash) != len(h.hash) || len(g.base) != len(h.base) {
+		t.Fatalf("Clone should copy the caches, have %v", g.hash)
+	}
+	g.Invalidate("testdata/base.ext")
+	_, ok := h.getHash("testdata/base.ext")
+	if !ok {
+		t.Errorf("Invalidate on a clone should not affect the original")
+	}
+	h.Reset()
+	_, ok = g.getHash("testdata/noext")
+	if !ok {
+		t.Errorf("Reset on the original should not affect a clone")
+	}
+}
+
+func TestReadDir(t *testing.T) {
+	m := fstest.MapFS{
+		"a.txt":     &fstest.MapFile{Data: []byte("a")},
+		"dir/b.txt": &fstest.MapFile{Data: []byte("b")},
+	}
+	h := New(m, WithHashLength(8))
+	entries, err := fs.ReadDir(h, ".")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"a.ca978112.txt", "dir"}
+	if len(entries) != len(want) {
+		t.Fatalf("ReadDir\nhave %v\nwant %v", entries, want)
+	}
+	for i, entry := range entries {
+		if entry.Name() != want[i] {
+			t.Errorf("ReadDir[%d]\nhave '%s'\nwant '%s'", i, entry.Name(), want[i])
+		}
+		info, err := entry.Info()
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			continue
+		}
+		if info.Name() != want[i] {
+			t.Errorf("Info()[%d]\nhave '%s'\nwant '%s'", i, info.Name(), want[i])
+		}
+	}
+}
+
+func TestOpenReadDir(t *testing.T) {
+	m := fstest.MapFS{
+		"a.js":     &fstest.MapFile{Data: []byte("a")},
+		"dir/b.js": &fstest.MapFile{Data: []byte("b")},
+	}
+	h := New(m, WithHashLength(8))
+	want := []string{"a.ca978112.js", "dir"}
+	for _, n := range []int{-1, 1} {
+		f, err := h.Open(".")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		d, ok := f.(fs.ReadDirFile)
+		if !ok {
+			t.Fatalf("Open should return a fs.ReadDirFile for directories")
+		}
+		var names []string
+		for {
+			entries, err := d.ReadDir(n)
+			for _, entry := range entries {
+				names = append(names, entry.Name())
+			}
+			if n < 0 || err != nil {
+				break
+			}
+		}
+		f.Close()
+		if strings.Join(names, " ") != strings.Join(want, " ") {
+			t.Errorf("ReadDir(%d)\nhave %v\nwant %v", n, names, want)
+		}
+	}
+}
+
+func TestGlob(t *testing.T) {
+	m := fstest.MapFS{
+		"a.txt":        &fstest.MapFile{Data: []byte("a")},
+		"b.js":         &fstest.MapFile{Data: []byte("b")},
+		"dir/a.txt":    &fstest.MapFile{Data: []byte("a")},
+		"dir/sub/b.js": &fstest.MapFile{Data: []byte("b")},
+	}
+	h := New(m, WithHashLength(8))
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{"*.txt", []string{"a.ca978112.txt"}},
+		{"*.ca978112.*", []string{"a.ca978112.txt"}},
+		{"*/*.txt", []string{"dir/a.ca978112.txt"}},
+		{"dir/*", []string{"dir/a.ca978112.txt", "dir/sub"}},
+		{"*/*/*.js", []string{"dir/sub/b.3e23e816.js"}},
+		{"*.css", nil},
+	}
+	for _, tt := range tests {
+		matches, err := fs.Glob(h, tt.pattern)
+		if err != nil {
+			t.Errorf("Glob(%q) unexpected error: %v", tt.pattern, err)
+			continue
+		}
+		if strings.Join(matches, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("Glob(%q)\nhave %v\nwant %v", tt.pattern, matches, tt.want)
+		}
+	}
+	_, err := h.Glob("[")
+	if !errors.Is(err, path.ErrBadPattern) {
+		t.Errorf("Glob should yield path.ErrBadPattern, have %v", err)
+	}
+}
+
+func TestFormatPrefixDir(t *testing.T) {
+	h := New(testdata, WithFormat(PrefixDir), WithHashLength(8))
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"testdata/base.ext", "d476fb7b/testdata/base.ext"},
+		{"testdata/noext", "d9d4e730/testdata/noext"},
+	}
+	for _, tt := range tests {
+		have := h.Name(tt.name)
+		if have != tt.want {
+			t.Errorf("Name(%q)\nhave '%s'\nwant '%s'", tt.name, have, tt.want)
+			continue
+		}
+		f, err := New(testdata, WithFormat(PrefixDir), WithHashLength(8)).Open(have)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			continue
+		}
+		f.Close()
+	}
+	for _, name := range []string{"88888888/testdata/base.ext", "/88888888/testdata/base.ext"} {
+		_, err := h.Open(name)
+		if err == nil {
+			t.Errorf("Open(%q) should error", name)
+		}
+	}
+}
+
+func TestSeparator(t *testing.T) {
+	h := New(testdata, WithSeparator("-"), WithHashLength(8))
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"testdata/base.ext", "testdata/base-d476fb7b.ext"},
+		{"testdata/noext", "testdata/noext-d9d4e730"},
+	}
+	for _, tt := range tests {
+		have := h.Name(tt.name)
+		if have != tt.want {
+			t.Errorf("Name(%q)\nhave '%s'\nwant '%s'", tt.name, have, tt.want)
+			continue
+		}
+		f, err := New(testdata, WithSeparator("-"), WithHashLength(8)).Open(have)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			continue
+		}
+		f.Close()
+	}
+	for _, name := range []string{"testdata/base.d476fb7b.ext", "testdata/base-88888888.ext"} {
+		_, err := h.Open(name)
+		if err == nil {
+			t.Errorf("Open(%q) should error", name)
+		}
+	}
+}
+
+func TestSeparatorDottedDir(t *testing.T) {
+	m := fstest.MapFS{"v1.2/noext": &fstest.MapFile{Data: []byte("a")}}
+	h := New(m, WithHashLength(8))
+	name := h.Name("v1.2/noext")
+	want := "v1.2/noext.ca978112"
+	if name != want {
+		t.Fatalf("Name\nhave '%s'\nwant '%s'", name, want)
+	}
+	f, err := New(m, WithHashLength(8)).Open(name)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	f.Close()
+}
+
+func TestSeparatorInvalid(t *testing.T) {
+	tests := []func(){
+		func() { WithSeparator("") },
+		func() { WithSeparator("/") },
+		func() { WithSeparator("\x00") },
+		func() { New(testdata, WithSeparator("a")) },
+		func() { New(testdata, WithSeparator("-"), WithEncoding(Base64URL)) },
+	}
+	for i, tt := range tests {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%d. should panic", i)
+				}
+			}()
+			tt()
+		}()
+	}
+}
+
+func TestBase(t *testing.T) {
+	tests := []struct {
+		name string
+		base string
+		ok   bool
+	}{
+		{"testdata/base.d476fb7be1b02ea9f66c797a0c11b11ff5db1bd702ff6c4720e455a301a501f1.ext", "testdata/base.ext", true},
+		{"testdata/noext.d9d4e730296e72377ae86529027f7defd5feecf4602a1f15f561cd4fae3644c5", "testdata/noext", true},
+		{"testdata/base.8888888888888888888888888888888888888888888888888888888888888888.ext", "", false},
+		{"testdata/base.ext", "", false},
+	}
+	h := New(testdata)
+	for _, tt := range tests {
+		base, ok := h.Base(tt.name)
+		if base != tt.base || ok != tt.ok {
+			t.Errorf("Base(%q)\nhave '%s', %t\nwant '%s', %t", tt.name, base, ok, tt.base, tt.ok)
+		}
+	}
+	if _, ok := h.getHash("testdata/base.ext"); !ok {
+		t.Errorf("Base should populate the cache")
+	}
+}
+
+func TestHashers(t *testing.T) {
+	h := New(testdata, WithHashers(sha512.New, sha256.New))
+	name := "testdata/base.ext"
+	want := "testdata/base.be05eecfcebe6adda9345d880d76cb1150971e698931b820bf53f9f88081a3d0498db1a0962dca6a70ce5d137f906de84f172e1e678bdf1bf39be98673fc15c7.ext"
+	have := h.Name(name)
+	if have != want {
+		t.Errorf("Name(%q)\nhave '%s'\nwant '%s'", name, have, want)
+	}
+	tests := []string{
+		"testdata/base.be05eecfcebe6adda9345d880d76cb1150971e698931b820bf53f9f88081a3d0498db1a0962dca6a70ce5d137f906de84f172e1e678bdf1bf39be98673fc15c7.ext",
+		"testdata/base.d476fb7be1b02ea9f66c797a0c11b11ff5db1bd702ff6c4720e455a301a501f1.ext",
+	}
+	for _, tt := range tests {
+		for i := 0; i < 2; i++ {
+			f, err := New(testdata, WithHashers(sha512.New, sha256.New)).Open(tt)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				continue
+			}
+			f.Close()
+		}
+	}
+	_, err := h.Open("testdata/base.8888888888888888888888888888888888888888888888888888888888888888.ext")
+	if !errors.Is(err, ErrHashMismatch) {
+		t.Errorf("Open should yield ErrHashMismatch, have %v", err)
+	}
+	if h.Name(name) != want {
+		t.Errorf("Open should not replace the primary digest")
+	}
+}
+
+func TestSlashSeparatedNames(t *testing.T) {
+	m := fstest.MapFS{
+		`dir.v1/a\b.txt`: &fstest.MapFile{Data: []byte("a")},
+		`a.b\noext`:      &fstest.MapFile{Data: []byte("a")},
+		"v1.2/noext":     &fstest.MapFile{Data: []byte("a")},
+	}
+	tests := []struct {
+		name string
+		want string
+	}{
+		{`dir.v1/a\b.txt`, `dir.v1/a\b.ca978112.txt`},
+		{`a.b\noext`, `a.ca978112.b\noext`},
+		{"v1.2/noext", "v1.2/noext.ca978112"},
+	}
+	h := New(m, WithHashLength(8))
+	for _, tt := range tests {
+		have := h.Name(tt.name)
+		if have != tt.want {
+			t.Errorf("Name(%q) on %s\nhave '%s'\nwant '%s'", tt.name, runtime.GOOS, have, tt.want)
+			continue
+		}
+		base, ok := New(m, WithHashLength(8)).Base(have)
+		if !ok || base != tt.name {
+			t.Errorf("Base(%q) on %s\nhave '%s'\nwant '%s'", have, runtime.GOOS, base, tt.name)
+		}
+	}
+}
+
+func TestCompoundExtensions(t *testing.T) {
+	m := fstest.MapFS{
+		"app.min.js":     &fstest.MapFile{Data: []byte("a")},
+		"types.d.ts":     &fstest.MapFile{Data: []byte("a")},
+		"archive.tar.gz": &fstest.MapFile{Data: []byte("a")},
+	}
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"app.min.js", "app.min.ca978112.js"},
+		{"types.d.ts", "types.d.ca978112.ts"},
+		{"archive.tar.gz", "archive.tar.ca978112.gz"},
+	}
+	h := New(m, WithHashLength(8))
+	for _, tt := range tests {
+		have := h.Name(tt.name)
+		if have != tt.want {
+			t.Errorf("Name(%q)\nhave '%s'\nwant '%s'", tt.name, have, tt.want)
+			continue
+		}
+		base, ok := New(m, WithHashLength(8)).Base(have)
+		if !ok || base != tt.name {
+			t.Errorf("Base(%q)\nhave '%s', %t\nwant '%s', true", have, base, ok, tt.name)
+		}
+	}
+	for _, name := range []string{"app.ca978112.min.js", "types.ca978112.d.ts", "archive.ca978112.tar.gz"} {
+		_, ok := h.Base(name)
+		if ok {
+			t.Errorf("Base(%q) should not resolve", name)
+		}
+	}
+}
+
+func TestWithExtSegments(t *testing.T) {
+	m := fstest.MapFS{
+		"js/app.chunk.js": &fstest.MapFile{Data: []byte("a")},
+		"app.js":          &fstest.MapFile{Data: []byte("a")},
+		"a.b.chunk.js":    &fstest.MapFile{Data: []byte("a")},
+	}
+	tests := []struct {
+		opts []Option
+		name string
+		want string
+	}{
+		{nil, "js/app.chunk.js", "js/app.chunk.ca978112.js"},
+		{[]Option{WithExtSegments(2)}, "js/app.chunk.js", "js/app.ca978112.chunk.js"},
+		{[]Option{WithExtSegments(2)}, "app.js", "app.ca978112.js"},
+		{[]Option{WithExtSegments(2)}, "a.b.chunk.js", "a.b.ca978112.chunk.js"},
+		{[]Option{WithExtSegments(2), WithNameTemplate("{base}-{hash}{ext}")}, "a.b.chunk.js", "a.b-ca978112.chunk.js"},
+	}
+	for i, tt := range tests {
+		opts := append([]Option{WithHashLength(8)}, tt.opts...)
+		have := New(m, opts...).Name(tt.name)
+		if have != tt.want {
+			t.Errorf("%d. Name(%q)\nhave '%s'\nwant '%s'", i, tt.name, have, tt.want)
+			continue
+		}
+		base, ok := New(m, opts...).Base(have)
+		if !ok || base != tt.name {
+			t.Errorf("%d. Base(%q)\nhave '%s', %t\nwant '%s', true", i, have, base, ok, tt.name)
+		}
+	}
+}
+
+func TestNewFromDir(t *testing.T) {
+	h := NewFromDir("testdata", WithHashLength(8))
+	name := h.Name("base.ext")
+	want := "base.d476fb7b.ext"
+	if name != want {
+		t.Errorf("Name\nhave '%s'\nwant '%s'", name, want)
+	}
+	_, err := h.HashErr("not-found")
+	var e *fs.PathError
+	if !errors.As(err, &e) {
+		t.Fatalf("HashErr should yield a *fs.PathError, have %v", err)
+	}
+	if e.Path != filepath.Join("testdata", "not-found") {
+		t.Errorf("Path\nhave '%s'\nwant '%s'", e.Path, filepath.Join("testdata", "not-found"))
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("HashErr should yield fs.ErrNotExist, have %v", err)
+	}
+}
+
+func TestHashBytes(t *testing.T) {
+	h := New(testdata)
+	hash := h.HashBytes("bundle.css", []byte("a"))
+	want := "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb"
+	if hash != want {
+		t.Errorf("HashBytes\nhave '%s'\nwant '%s'", hash, want)
+	}
+	name := h.Name("bundle.css")
+	if name != "bundle."+want+".css" {
+		t.Errorf("Name\nhave '%s'\nwant '%s'", name, "bundle."+want+".css")
+	}
+	base, ok := h.Base(name)
+	if !ok || base != "bundle.css" {
+		t.Errorf("Base(%q)\nhave '%s', %t\nwant 'bundle.css', true", name, base, ok)
+	}
+}
+
+func TestHashReader(t *testing.T) {
+	h := New(testdata, WithHashLength(8))
+	hash, err := h.HashReader("bundle.css", strings.NewReader("a"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if hash != "ca978112" {
+		t.Errorf("HashReader\nhave '%s'\nwant '%s'", hash, "ca978112")
+	}
+	name := h.Name("bundle.css")
+	if name != "bundle.ca978112.css" {
+		t.Errorf("Name\nhave '%s'\nwant '%s'", name, "bundle.ca978112.css")
+	}
+	want := errors.New("read failed")
+	_, err = h.HashReader("other.css", iotest.ErrReader(want))
+	if !errors.Is(err, want) {
+		t.Errorf("HashReader\nhave %v\nwant %v", err, want)
+	}
+}
+
+func TestCaseInsensitive(t *testing.T) {
+	name := "testdata/base.D476FB7BE1B02EA9F66C797A0C11B11FF5DB1BD702FF6C4720E455A301A501F1.ext"
+	tests := []struct {
+		fold bool
+		err  error
+	}{
+		{false, ErrHashMismatch},
+		{true, nil},
+	}
+	for _, tt := range tests {
+		h := New(testdata, WithCaseInsensitive(tt.fold))
+		for i := 0; i < 2; i++ {
+			f, err := h.Open(name)
+			if !errors.Is(err, tt.err) {
+				t.Errorf("WithCaseInsensitive(%t) should yield %v, have %v", tt.fold, tt.err, err)
+				continue
+			}
+			if err == nil {
+				f.Close()
+			}
+		}
+		if tt.fold {
+			if _, ok := h.getBase(name); !ok {
+				t.Errorf("WithCaseInsensitive should cache the requested name")
+			}
+			want := "testdata/base.d476fb7be1b02ea9f66c797a0c11b11ff5db1bd702ff6c4720e455a301a501f1.ext"
+			if h.Name("testdata/base.ext") != want {
+				t.Errorf("Name should emit lowercase digests")
+			}
+		}
+	}
+}
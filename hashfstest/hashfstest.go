@@ -0,0 +1,43 @@
+// Package hashfstest implements support for testing hashfs setups.
+package hashfstest
+
+import (
+	"bytes"
+	"io/fs"
+	"testing"
+
+	"github.com/pnelson/hashfs"
+)
+
+// AssertRoundTrip walks f and asserts that every file opens by its
+// hashed name, as returned by Name, with the same content as by its
+// plain name. The FS must serve plain names, so WithStrictHashed
+// must not be set.
+func AssertRoundTrip(tb testing.TB, f *hashfs.FS) {
+	tb.Helper()
+	err := f.WalkHashed(func(hashedPath string, d fs.DirEntry) error {
+		base, ok := f.Base(hashedPath)
+		if !ok {
+			// Not hashable per WithExtensions.
+			base = hashedPath
+		}
+		want, err := fs.ReadFile(f, base)
+		if err != nil {
+			tb.Errorf("hashfstest: open %s: %v", base, err)
+			return nil
+		}
+		name := f.Name(base)
+		have, err := fs.ReadFile(f, name)
+		if err != nil {
+			tb.Errorf("hashfstest: open %s as %s: %v", base, name, err)
+			return nil
+		}
+		if !bytes.Equal(have, want) {
+			tb.Errorf("hashfstest: %s content differs from %s", name, base)
+		}
+		return nil
+	})
+	if err != nil {
+		tb.Fatalf("hashfstest: %v", err)
+	}
+}
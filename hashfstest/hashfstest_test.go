@@ -0,0 +1,48 @@
+package hashfstest
+
+import (
+	"fmt"
+	"testing"
+	"testing/fstest"
+
+	"github.com/pnelson/hashfs"
+)
+
+type testTB struct {
+	testing.TB
+	errors []string
+}
+
+func (tb *testTB) Helper() {}
+
+func (tb *testTB) Errorf(format string, args ...interface{}) {
+	tb.errors = append(tb.errors, fmt.Sprintf(format, args...))
+}
+
+func (tb *testTB) Fatalf(format string, args ...interface{}) {
+	tb.errors = append(tb.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertRoundTrip(t *testing.T) {
+	m := fstest.MapFS{
+		"a.js":         &fstest.MapFile{Data: []byte("a")},
+		"css/b.css":    &fstest.MapFile{Data: []byte("b")},
+		"fonts/c.woff": &fstest.MapFile{Data: []byte("c")},
+	}
+	tests := []struct {
+		opts []hashfs.Option
+		want int
+	}{
+		{nil, 0},
+		{[]hashfs.Option{hashfs.WithHashLength(8), hashfs.WithURLPrefix("/static/")}, 0},
+		{[]hashfs.Option{hashfs.WithExtensions(".js", ".css")}, 0},
+		{[]hashfs.Option{hashfs.WithStrictHashed(true)}, 3},
+	}
+	for i, tt := range tests {
+		tb := &testTB{TB: t}
+		AssertRoundTrip(tb, hashfs.New(m, tt.opts...))
+		if len(tb.errors) != tt.want {
+			t.Errorf("%d. errors\nhave %q\nwant %d", i, tb.errors, tt.want)
+		}
+	}
+}
@@ -0,0 +1,349 @@
+package hashfs
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FileServer returns a http.Handler that serves files from the FS.
+// Responses for hashed file names are marked as immutable with an
+// ETag derived from the digest and honor conditional requests.
+// Precompressed variants are served to clients that accept them.
+// Range requests are supported for hashed file names, such as for
+// seeking in audio and video assets.
+// Directory requests are served the index file set by WithIndexFile.
+// HTML responses link the assets set by WithPreload.
+func (f *FS) FileServer() http.Handler {
+	fileServer := http.FileServer(f.HTTPFileSystem())
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if f.preload != nil {
+			w = &preloadWriter{ResponseWriter: w, links: f.preloadLinks()}
+		}
+		name := strings.TrimPrefix(r.URL.Path, "/")
+		if f.indexFile != "" && f.serveIndex(w, r, name) {
+			return
+		}
+		base, err := f.resolve(name)
+		if err != nil {
+			// Mismatched names may still be plain files.
+			fileServer.ServeHTTP(w, r)
+			return
+		}
+		hash, _ := f.getHash(base)
+		var modtime time.Time
+		info, err := fs.Stat(f.fs, base)
+		if err == nil {
+			modtime = info.ModTime()
+		}
+		h := w.Header()
+		f.setCacheHeaders(h, base)
+		encoding, b, ok := f.variant(base, r.Header.Get("Accept-Encoding"))
+		etag := strconv.Quote(hash)
+		if ok {
+			etag = strconv.Quote(hash + "-" + encoding)
+		}
+		h.Set("ETag", etag)
+		if notModified(r, etag, modtime) {
+			if !modtime.IsZero() {
+				h.Set("Last-Modified", modtime.UTC().Format(http.TimeFormat))
+			}
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		if !ok {
+			f.serveFile(w, r, base, modtime)
+			return
+		}
+		ctype := mime.TypeByExtension(path.Ext(base))
+		if ctype == "" {
+			ctype = "application/octet-stream"
+		}
+		h.Set("Content-Type", ctype)
+		h.Set("Content-Encoding", encoding)
+		http.ServeContent(w, r, name, modtime, bytes.NewReader(b))
+	})
+}
+
+// serveFile serves the given hashed file, supporting range requests.
+// Files that are not seekable are read into memory.
+func (f *FS) serveFile(w http.ResponseWriter, r *http.Request, name string, modtime time.Time) {
+	var file fs.File
+	c, ok := f.content.get(name)
+	if ok {
+		file = newMemFile(c)
+	} else {
+		var err error
+		file, err = f.fs.Open(name)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+	}
+	defer file.Close()
+	rs, ok := file.(io.ReadSeeker)
+	if !ok {
+		b, err := io.ReadAll(file)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		rs = bytes.NewReader(b)
+	}
+	http.ServeContent(w, r, name, modtime, rs)
+}
+
+// WithCachePolicy sets the Cache-Control values that FileServer uses for
+// hashed files by extension, such as ".json". Other hashed files are
+// cached as immutable for a year.
+func WithCachePolicy(policy map[string]string) Option {
+	return func(f *FS) {
+		f.policy = make(map[string]string, len(policy))
+		for ext, value := range policy {
+			if !strings.HasPrefix(ext, ".") {
+				ext = "." + ext
+			}
+			f.policy[strings.ToLower(ext)] = value
+		}
+	}
+}
+
+// cacheControl returns the Cache-Control value for the given hashed file.
+func (f *FS) cacheControl(name string) string {
+	value, ok := f.policy[strings.ToLower(path.Ext(name))]
+	if ok {
+		return value
+	}
+	return "public, max-age=31536000, immutable"
+}
+
+// setCacheHeaders sets the caching headers of the given hashed file.
+func (f *FS) setCacheHeaders(h http.Header, name string) {
+	h.Set("Cache-Control", f.cacheControl(name))
+	if len(f.compressors) > 0 {
+		h.Add("Vary", "Accept-Encoding")
+	}
+}
+
+// RedirectMiddleware returns a http.Handler that serves requests with
+// next, setting the caching headers of FileServer on successful responses
+// to hashed paths. Redirects and other responses are left intact, so the
+// middleware composes with routers and handlers other than FileServer.
+func (f *FS) RedirectMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		base, err := f.resolve(strings.TrimPrefix(r.URL.Path, "/"))
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(&cacheWriter{ResponseWriter: w, fsys: f, name: base}, r)
+	})
+}
+
+// cacheWriter is a http.ResponseWriter that sets the
+// caching headers of a hashed file on successful responses.
+type cacheWriter struct {
+	http.ResponseWriter
+	fsys  *FS
+	name  string
+	wrote bool
+}
+
+// WriteHeader implements the http.ResponseWriter interface.
+func (w *cacheWriter) WriteHeader(code int) {
+	if !w.wrote {
+		w.wrote = true
+		if code < http.StatusMultipleChoices || code == http.StatusNotModified {
+			w.fsys.setCacheHeaders(w.Header(), w.name)
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write implements the http.ResponseWriter interface.
+func (w *cacheWriter) Write(b []byte) (int, error) {
+	if !w.wrote {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// CacheControlInfo is implemented by the fs.FileInfo of files opened
+// by hashed file name, so that generic static file servers can discover
+// the Cache-Control value that FileServer would use.
+type CacheControlInfo interface {
+	fs.FileInfo
+	CacheControl() string
+}
+
+// cacheInfo is a fileInfo that reports the Cache-Control value.
+type cacheInfo struct {
+	fileInfo
+	cacheControl string
+}
+
+// CacheControl implements the CacheControlInfo interface.
+func (fi cacheInfo) CacheControl() string {
+	return fi.cacheControl
+}
+
+// serveIndex serves the index file for the given directory, responding
+// with 404 if the directory has none. Returns false if the given name is
+// not a directory.
+func (f *FS) serveIndex(w http.ResponseWriter, r *http.Request, name string) bool {
+	dir := path.Clean(f.trimPrefix(name))
+	if dir == "/" || dir == "" {
+		dir = "."
+	}
+	info, err := fs.Stat(f.fs, dir)
+	if err != nil || !info.IsDir() {
+		return false
+	}
+	if !strings.HasSuffix(r.URL.Path, "/") {
+		u := *r.URL
+		u.Path += "/"
+		http.Redirect(w, r, u.String(), http.StatusMovedPermanently)
+		return true
+	}
+	b, err := fs.ReadFile(f.fs, path.Join(dir, f.indexFile))
+	if err != nil {
+		http.NotFound(w, r)
+		return true
+	}
+	var modtime time.Time
+	info, err = fs.Stat(f.fs, path.Join(dir, f.indexFile))
+	if err == nil {
+		modtime = info.ModTime()
+	}
+	http.ServeContent(w, r, f.indexFile, modtime, bytes.NewReader(b))
+	return true
+}
+
+// notModified reports whether the conditional request headers
+// indicate that the client has the current representation.
+func notModified(r *http.Request, etag string, modtime time.Time) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	inm := r.Header.Get("If-None-Match")
+	if inm != "" {
+		for _, tag := range strings.Split(inm, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+				return true
+			}
+		}
+		return false
+	}
+	ims := r.Header.Get("If-Modified-Since")
+	if ims == "" || modtime.IsZero() {
+		return false
+	}
+	t, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+	return !modtime.Truncate(time.Second).After(t)
+}
+
+// WithIndexFile sets the file, such as "index.html", that FileServer
+// serves for directory requests instead of a directory listing.
+// Directories without the file are not found.
+func WithIndexFile(name string) Option {
+	return func(f *FS) {
+		f.indexFile = name
+	}
+}
+
+// HTTPFileSystem returns a http.FileSystem that resolves hashed request
+// paths to their base files. Unlike http.FS(f), directories list their
+// files by hashed name, so that the listings of http.FileServer link to
+// hashed paths. Files report their hashed names in Stat.
+func (f *FS) HTTPFileSystem() http.FileSystem {
+	return httpFS{fsys: f}
+}
+
+// httpFS is a http.FileSystem adapter for a FS.
+type httpFS struct {
+	fsys *FS
+}
+
+// Open implements the http.FileSystem interface.
+func (h httpFS) Open(name string) (http.File, error) {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if name == "" {
+		name = "."
+	}
+	file, err := h.fsys.Open(name)
+	if errors.Is(err, ErrHashMismatch) {
+		// Reported as missing rather than an internal error.
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &httpFile{File: file, fsys: h.fsys, name: name, dir: info.IsDir()}, nil
+}
+
+// httpFile is a http.File adapter for a fs.File.
+type httpFile struct {
+	fs.File
+	fsys    *FS
+	name    string
+	dir     bool
+	entries []fs.DirEntry
+	read    bool
+}
+
+// Seek implements the io.Seeker interface.
+func (f *httpFile) Seek(offset int64, whence int) (int64, error) {
+	s, ok := f.File.(io.Seeker)
+	if !ok {
+		return 0, &fs.PathError{Op: "seek", Path: f.name, Err: fs.ErrInvalid}
+	}
+	return s.Seek(offset, whence)
+}
+
+// Readdir implements the http.File interface.
+func (f *httpFile) Readdir(count int) ([]fs.FileInfo, error) {
+	if !f.dir {
+		return nil, &fs.PathError{Op: "readdir", Path: f.name, Err: errors.New("not a directory")}
+	}
+	if !f.read {
+		entries, err := f.fsys.ReadDir(f.name)
+		if err != nil {
+			return nil, err
+		}
+		f.entries = entries
+		f.read = true
+	}
+	n := len(f.entries)
+	if count > 0 && count < n {
+		n = count
+	}
+	if count > 0 && n == 0 {
+		return nil, io.EOF
+	}
+	infos := make([]fs.FileInfo, 0, n)
+	for _, entry := range f.entries[:n] {
+		info, err := entry.Info()
+		if err != nil {
+			return infos, err
+		}
+		infos = append(infos, info)
+	}
+	f.entries = f.entries[n:]
+	return infos, nil
+}
@@ -0,0 +1,367 @@
+package hashfs
+
+import (
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestFileServer(t *testing.T) {
+	h := New(testdata)
+	r := httptest.NewRequest(http.MethodGet, "/testdata/base.d476fb7be1b02ea9f66c797a0c11b11ff5db1bd702ff6c4720e455a301a501f1.ext", nil)
+	w := httptest.NewRecorder()
+	h.FileServer().ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status\nhave %d\nwant %d", w.Code, http.StatusOK)
+	}
+	if w.Body.String() != "base.ext\n" {
+		t.Errorf("body\nhave %q\nwant %q", w.Body.String(), "base.ext\n")
+	}
+	cacheControl := w.Header().Get("Cache-Control")
+	if cacheControl != "public, max-age=31536000, immutable" {
+		t.Errorf("Cache-Control\nhave '%s'\nwant '%s'", cacheControl, "public, max-age=31536000, immutable")
+	}
+	etag := w.Header().Get("ETag")
+	if etag != `"d476fb7be1b02ea9f66c797a0c11b11ff5db1bd702ff6c4720e455a301a501f1"` {
+		t.Errorf("ETag\nhave '%s'", etag)
+	}
+}
+
+func TestFileServerNotHashed(t *testing.T) {
+	h := New(testdata)
+	r := httptest.NewRequest(http.MethodGet, "/testdata/base.ext", nil)
+	w := httptest.NewRecorder()
+	h.FileServer().ServeHTTP(w, r)
+	if w.Header().Get("Cache-Control") != "" {
+		t.Errorf("Cache-Control should not be set for non-hashed paths")
+	}
+	if w.Header().Get("ETag") != "" {
+		t.Errorf("ETag should not be set for non-hashed paths")
+	}
+}
+
+func TestFileServerNotModified(t *testing.T) {
+	modtime := time.Date(2021, 9, 3, 0, 0, 0, 0, time.UTC)
+	m := fstest.MapFS{"a.txt": &fstest.MapFile{Data: []byte("a"), ModTime: modtime}}
+	h := New(m)
+	etag := `"ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb"`
+	tests := []struct {
+		header string
+		value  string
+		want   int
+	}{
+		{"If-None-Match", etag, http.StatusNotModified},
+		{"If-None-Match", "W/" + etag, http.StatusNotModified},
+		{"If-None-Match", `"other", ` + etag, http.StatusNotModified},
+		{"If-None-Match", "*", http.StatusNotModified},
+		{"If-None-Match", `"other"`, http.StatusOK},
+		{"If-Modified-Since", modtime.Format(http.TimeFormat), http.StatusNotModified},
+		{"If-Modified-Since", modtime.Add(time.Hour).Format(http.TimeFormat), http.StatusNotModified},
+		{"If-Modified-Since", modtime.Add(-time.Hour).Format(http.TimeFormat), http.StatusOK},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/"+h.Name("a.txt"), nil)
+		r.Header.Set(tt.header, tt.value)
+		w := httptest.NewRecorder()
+		h.FileServer().ServeHTTP(w, r)
+		if w.Code != tt.want {
+			t.Errorf("%s: %s\nhave %d\nwant %d", tt.header, tt.value, w.Code, tt.want)
+			continue
+		}
+		if w.Code == http.StatusNotModified && w.Body.Len() != 0 {
+			t.Errorf("%s: %s should not have a body", tt.header, tt.value)
+		}
+	}
+}
+
+func TestFileServerHashMismatch(t *testing.T) {
+	h := New(testdata)
+	r := httptest.NewRequest(http.MethodGet, "/testdata/base.8888888888888888888888888888888888888888888888888888888888888888.ext", nil)
+	w := httptest.NewRecorder()
+	h.FileServer().ServeHTTP(w, r)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status\nhave %d\nwant %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestFileServerPlainDotted(t *testing.T) {
+	m := fstest.MapFS{
+		"app.js":     &fstest.MapFile{Data: []byte("a")},
+		"app.min.js": &fstest.MapFile{Data: []byte("b")},
+	}
+	h := New(m)
+	r := httptest.NewRequest(http.MethodGet, "/app.min.js", nil)
+	w := httptest.NewRecorder()
+	h.FileServer().ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status\nhave %d\nwant %d", w.Code, http.StatusOK)
+	}
+	if w.Body.String() != "b" {
+		t.Errorf("body\nhave %q\nwant %q", w.Body.String(), "b")
+	}
+}
+
+func TestHTTPFileSystem(t *testing.T) {
+	h := New(testdata)
+	handler := http.FileServer(h.HTTPFileSystem())
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/testdata/base.d476fb7be1b02ea9f66c797a0c11b11ff5db1bd702ff6c4720e455a301a501f1.ext", http.StatusOK, "base.ext\n"},
+		{"/testdata/base.8888888888888888888888888888888888888888888888888888888888888888.ext", http.StatusNotFound, ""},
+		{"/testdata/base.ext", http.StatusOK, "base.ext\n"},
+		{"/testdata", http.StatusMovedPermanently, ""},
+		{"/testdata/", http.StatusOK, "base.d476fb7be1b02ea9f66c797a0c11b11ff5db1bd702ff6c4720e455a301a501f1.ext"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != tt.code {
+			t.Errorf("%s status\nhave %d\nwant %d", tt.path, w.Code, tt.code)
+			continue
+		}
+		if !strings.Contains(w.Body.String(), tt.body) {
+			t.Errorf("%s body\nhave %q\nwant %q", tt.path, w.Body.String(), tt.body)
+		}
+	}
+}
+
+func TestFileServerIndexFile(t *testing.T) {
+	m := fstest.MapFS{
+		"index.html":      &fstest.MapFile{Data: []byte("root")},
+		"docs/index.html": &fstest.MapFile{Data: []byte("docs")},
+		"empty/a.txt":     &fstest.MapFile{Data: []byte("a")},
+	}
+	h := New(m, WithIndexFile("index.html"))
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/", http.StatusOK, "root"},
+		{"/docs/", http.StatusOK, "docs"},
+		{"/docs", http.StatusMovedPermanently, ""},
+		{"/empty/", http.StatusNotFound, ""},
+		{"/a.ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb.txt", http.StatusNotFound, ""},
+		{"/empty/a.ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb.txt", http.StatusOK, "a"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		w := httptest.NewRecorder()
+		h.FileServer().ServeHTTP(w, r)
+		if w.Code != tt.code {
+			t.Errorf("%s status\nhave %d\nwant %d", tt.path, w.Code, tt.code)
+			continue
+		}
+		if tt.body != "" && w.Body.String() != tt.body {
+			t.Errorf("%s body\nhave %q\nwant %q", tt.path, w.Body.String(), tt.body)
+		}
+	}
+}
+
+func TestFileServerPreload(t *testing.T) {
+	m := fstest.MapFS{
+		"index.html": &fstest.MapFile{Data: []byte("<html></html>")},
+		"app.js":     &fstest.MapFile{Data: []byte("a")},
+		"app.css":    &fstest.MapFile{Data: []byte("b")},
+	}
+	h := New(m, WithPreload("app.js", "app.css", "missing.js"))
+	want := []string{
+		"</app.ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb.js>; rel=preload; as=script",
+		"</app.3e23e8160039594a33894f6564e1b1348bbd7a0088d42c4acb73eeaed59c009d.css>; rel=preload; as=style",
+	}
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+	h.FileServer().ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status\nhave %d\nwant %d", w.Code, http.StatusOK)
+	}
+	links := w.Header().Values("Link")
+	if strings.Join(links, ",") != strings.Join(want, ",") {
+		t.Errorf("Link\nhave %q\nwant %q", links, want)
+	}
+	r = httptest.NewRequest(http.MethodGet, "/app.ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb.js", nil)
+	w = httptest.NewRecorder()
+	h.FileServer().ServeHTTP(w, r)
+	if w.Header().Get("Link") != "" {
+		t.Errorf("Link should only be set on HTML responses")
+	}
+}
+
+func TestFileServerCachePolicy(t *testing.T) {
+	m := fstest.MapFS{
+		"a.json": &fstest.MapFile{Data: []byte("a")},
+		"a.js":   &fstest.MapFile{Data: []byte("a")},
+	}
+	h := New(m, WithCachePolicy(map[string]string{"JSON": "public, max-age=60"}))
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/a.ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb.json", "public, max-age=60"},
+		{"/a.ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb.js", "public, max-age=31536000, immutable"},
+		{"/a.json", ""},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		w := httptest.NewRecorder()
+		h.FileServer().ServeHTTP(w, r)
+		have := w.Header().Get("Cache-Control")
+		if have != tt.want {
+			t.Errorf("%s Cache-Control\nhave '%s'\nwant '%s'", tt.path, have, tt.want)
+		}
+	}
+}
+
+func TestRedirectMiddleware(t *testing.T) {
+	m := fstest.MapFS{"a.txt": &fstest.MapFile{Data: []byte("a")}}
+	h := New(m)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/static", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/static/", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	handler := h.RedirectMiddleware(mux)
+	tests := []struct {
+		path   string
+		status int
+		want   string
+	}{
+		{"/" + h.Name("a.txt"), http.StatusOK, "public, max-age=31536000, immutable"},
+		{"/a.txt", http.StatusOK, ""},
+		{"/static", http.StatusMovedPermanently, ""},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != tt.status {
+			t.Errorf("%s status\nhave %d\nwant %d", tt.path, w.Code, tt.status)
+		}
+		have := w.Header().Get("Cache-Control")
+		if have != tt.want {
+			t.Errorf("%s Cache-Control\nhave '%s'\nwant '%s'", tt.path, have, tt.want)
+		}
+	}
+}
+
+type noSeekFS struct {
+	fstest.MapFS
+}
+
+func (m noSeekFS) Open(name string) (fs.File, error) {
+	f, err := m.MapFS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return struct{ fs.File }{f}, nil
+}
+
+func TestFileServerRange(t *testing.T) {
+	m := fstest.MapFS{"a.mp4": &fstest.MapFile{Data: []byte("0123456789")}}
+	for _, fsys := range []fs.FS{m, noSeekFS{m}} {
+		h := New(fsys, WithHashLength(8))
+		r := httptest.NewRequest(http.MethodGet, "/"+h.Name("a.mp4"), nil)
+		r.Header.Set("Range", "bytes=2-5")
+		w := httptest.NewRecorder()
+		h.FileServer().ServeHTTP(w, r)
+		if w.Code != http.StatusPartialContent {
+			t.Errorf("%T status\nhave %d\nwant %d", fsys, w.Code, http.StatusPartialContent)
+			continue
+		}
+		if w.Body.String() != "2345" {
+			t.Errorf("%T body\nhave %q\nwant %q", fsys, w.Body.String(), "2345")
+		}
+		if w.Header().Get("Content-Range") != "bytes 2-5/10" {
+			t.Errorf("%T Content-Range\nhave '%s'", fsys, w.Header().Get("Content-Range"))
+		}
+	}
+}
+
+func TestCacheControlInfo(t *testing.T) {
+	m := fstest.MapFS{"a.json": &fstest.MapFile{Data: []byte("a")}}
+	tests := []struct {
+		opts []Option
+		want string
+	}{
+		{nil, "public, max-age=31536000, immutable"},
+		{[]Option{WithCachePolicy(map[string]string{".json": "public, max-age=60"})}, "public, max-age=60"},
+		{[]Option{WithFormat(PrefixDir)}, "public, max-age=31536000, immutable"},
+	}
+	for i, tt := range tests {
+		h := New(m, tt.opts...)
+		f, err := h.Open(h.Name("a.json"))
+		if err != nil {
+			t.Fatalf("%d. unexpected error: %v", i, err)
+		}
+		info, err := f.Stat()
+		f.Close()
+		if err != nil {
+			t.Fatalf("%d. unexpected error: %v", i, err)
+		}
+		cc, ok := info.(CacheControlInfo)
+		if !ok {
+			t.Errorf("%d. Stat should implement CacheControlInfo", i)
+			continue
+		}
+		if cc.CacheControl() != tt.want {
+			t.Errorf("%d. CacheControl\nhave '%s'\nwant '%s'", i, cc.CacheControl(), tt.want)
+		}
+		info, err = fs.Stat(h, h.Name("a.json"))
+		if err != nil {
+			t.Fatalf("%d. unexpected error: %v", i, err)
+		}
+		cc, ok = info.(CacheControlInfo)
+		if !ok {
+			t.Errorf("%d. fs.Stat should implement CacheControlInfo", i)
+			continue
+		}
+		if cc.CacheControl() != tt.want {
+			t.Errorf("%d. fs.Stat CacheControl\nhave '%s'\nwant '%s'", i, cc.CacheControl(), tt.want)
+		}
+		hf, err := h.HTTPFileSystem().Open(h.Name("a.json"))
+		if err != nil {
+			t.Fatalf("%d. unexpected error: %v", i, err)
+		}
+		info, err = hf.Stat()
+		hf.Close()
+		if err != nil {
+			t.Fatalf("%d. unexpected error: %v", i, err)
+		}
+		if info.Name() != path.Base(h.Name("a.json")) {
+			t.Errorf("%d. http.File Stat Name\nhave '%s'\nwant '%s'", i, info.Name(), path.Base(h.Name("a.json")))
+		}
+		_, ok = info.(CacheControlInfo)
+		if !ok {
+			t.Errorf("%d. http.File Stat should implement CacheControlInfo", i)
+		}
+	}
+	info, err := fs.Stat(New(m), "a.json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, ok := info.(CacheControlInfo)
+	if ok {
+		t.Errorf("fs.Stat of plain names should not implement CacheControlInfo")
+	}
+	f, err := New(m).Open("a.json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer f.Close()
+	info, _ = f.Stat()
+	_, ok = info.(CacheControlInfo)
+	if ok {
+		t.Errorf("Stat of plain names should not implement CacheControlInfo")
+	}
+}
@@ -0,0 +1,89 @@
+package hashfs
+
+import (
+	"sort"
+)
+
+// WithContentIndex maintains an index of digests to the files with
+// that content as files are hashed, such as by WarmCache. Open then
+// also resolves a bare digest to the first such file in lexical order.
+func WithContentIndex(enabled bool) Option {
+	return func(f *FS) {
+		f.index = nil
+		if enabled {
+			f.index = make(map[string][]string)
+		}
+	}
+}
+
+// addIndex adds the given file to the content index.
+// The lock must be held.
+func (f *FS) addIndex(name, hash string) {
+	if f.index == nil {
+		return
+	}
+	names := f.index[hash]
+	i := sort.SearchStrings(names, name)
+	if i < len(names) && names[i] == name {
+		return
+	}
+	names = append(names, "")
+	copy(names[i+1:], names[i:])
+	names[i] = name
+	f.index[hash] = names
+}
+
+// removeIndex removes the given file from the content index.
+// The lock must be held.
+func (f *FS) removeIndex(name, hash string) {
+	if f.index == nil {
+		return
+	}
+	names := f.index[hash]
+	i := sort.SearchStrings(names, name)
+	if i == len(names) || names[i] != name {
+		return
+	}
+	names = append(names[:i:i], names[i+1:]...)
+	if len(names) == 0 {
+		delete(f.index, hash)
+		return
+	}
+	f.index[hash] = names
+}
+
+// getIndex performs a synchronized lookup on the content index.
+func (f *FS) getIndex(hash string) (string, bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	names, ok := f.index[hash]
+	if !ok {
+		return "", false
+	}
+	return names[0], true
+}
+
+// Duplicates returns a mapping of digests to the original file names
+// sharing that digest, for digests shared by more than one of the files
+// hashed so far. Use WarmCache first to consider every file.
+func (f *FS) Duplicates() map[string][]string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	index := f.index
+	if index == nil {
+		index = make(map[string][]string)
+		for name, hash := range f.hash {
+			index[hash] = append(index[hash], name)
+		}
+	}
+	m := make(map[string][]string)
+	for hash, names := range index {
+		if len(names) < 2 {
+			continue
+		}
+		names = append([]string(nil), names...)
+		sort.Strings(names)
+		m[hash] = names
+	}
+	return m
+}
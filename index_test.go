@@ -0,0 +1,95 @@
+package hashfs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func TestContentIndex(t *testing.T) {
+	m := fstest.MapFS{
+		"b.txt":     &fstest.MapFile{Data: []byte("a")},
+		"dir/a.txt": &fstest.MapFile{Data: []byte("a")},
+		"c.txt":     &fstest.MapFile{Data: []byte("c")},
+	}
+	h := New(m, WithContentIndex(true))
+	err := h.WarmCache()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb", "a"},
+		{"2e7d2c03a9507ae265ecf5b5356885a53393a2029d241394997265a1a25aefc6", "c"},
+	}
+	for _, tt := range tests {
+		f, err := h.Open(tt.name)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			continue
+		}
+		b, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			continue
+		}
+		if string(b) != tt.want {
+			t.Errorf("Open(%q)\nhave %q\nwant %q", tt.name, b, tt.want)
+		}
+	}
+	base, _ := h.Base("ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb")
+	if base != "b.txt" {
+		t.Errorf("Base\nhave '%s'\nwant '%s'", base, "b.txt")
+	}
+	h.Invalidate("b.txt")
+	base, _ = h.Base("ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb")
+	if base != "dir/a.txt" {
+		t.Errorf("Base after Invalidate\nhave '%s'\nwant '%s'", base, "dir/a.txt")
+	}
+}
+
+func TestContentIndexDisabled(t *testing.T) {
+	m := fstest.MapFS{"a.txt": &fstest.MapFile{Data: []byte("a")}}
+	h := New(m)
+	err := h.WarmCache()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = h.Open("ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb")
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Open should yield fs.ErrNotExist, have %v", err)
+	}
+}
+
+func TestDuplicates(t *testing.T) {
+	m := fstest.MapFS{
+		"b.txt":     &fstest.MapFile{Data: []byte("a")},
+		"dir/a.txt": &fstest.MapFile{Data: []byte("a")},
+		"c.txt":     &fstest.MapFile{Data: []byte("c")},
+	}
+	for _, enabled := range []bool{false, true} {
+		h := New(m, WithContentIndex(enabled))
+		err := h.WarmCache()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		dups := h.Duplicates()
+		if len(dups) != 1 {
+			t.Errorf("WithContentIndex(%t) Duplicates\nhave %v", enabled, dups)
+			continue
+		}
+		names := dups["ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb"]
+		if len(names) != 2 || names[0] != "b.txt" || names[1] != "dir/a.txt" {
+			t.Errorf("WithContentIndex(%t) Duplicates\nhave %v", enabled, names)
+		}
+		names[0] = "mutated"
+		if h.Duplicates()["ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb"][0] != "b.txt" {
+			t.Errorf("Duplicates should return a copy")
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package hashfs
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"strings"
+)
+
+// integrityHashes maps the Subresource Integrity algorithms
+// to their hash.Hash constructors.
+var integrityHashes = map[string]func() hash.Hash{
+	"sha256": sha256.New,
+	"sha384": sha512.New384,
+	"sha512": sha512.New,
+}
+
+// Integrity returns the Subresource Integrity value of the given file,
+// such as "sha256-<base64 digest>". Returns an empty string if the
+// file could not be hashed, or panics per WithErrorMode.
+func (f *FS) Integrity(name string) string {
+	integrity, err := f.integrity(name)
+	if err != nil && f.errorMode == ErrorModePanic {
+		panic(err)
+	}
+	return integrity
+}
+
+// integrity returns the Subresource Integrity value of the given file
+// or the error encountered while reading it.
+func (f *FS) integrity(name string) (string, error) {
+	if f.alg == "" {
+		// The configured hasher is not a known integrity algorithm,
+		// compute sha256 without using the cache.
+		sum, err := f.digest(context.Background(), name, sha256.New())
+		if err != nil {
+			return "", err
+		}
+		return "sha256-" + base64.StdEncoding.EncodeToString(sum), nil
+	}
+	if f.normalizesText(name) {
+		// The cached digest is not of the served content.
+		sum, err := f.digest(context.Background(), name, f.newHash())
+		if err != nil {
+			return "", err
+		}
+		return f.alg + "-" + base64.StdEncoding.EncodeToString(sum), nil
+	}
+	sum, err := f.rawHash(name)
+	if err != nil {
+		return "", err
+	}
+	return f.alg + "-" + base64.StdEncoding.EncodeToString(sum), nil
+}
+
+// IntegritySet returns the space separated Subresource Integrity values
+// of the given file for each of the given algorithms, such as "sha256"
+// and "sha512", reading the file once. Defaults to sha256 if no
+// algorithms are given.
+func (f *FS) IntegritySet(name string, algs ...string) (string, error) {
+	if len(algs) == 0 {
+		algs = []string{"sha256"}
+	}
+	hashes := make([]hash.Hash, len(algs))
+	writers := make([]io.Writer, len(algs))
+	for i, alg := range algs {
+		fn, ok := integrityHashes[alg]
+		if !ok {
+			return "", fmt.Errorf("hashfs: unknown integrity algorithm %q", alg)
+		}
+		hashes[i] = fn()
+		writers[i] = hashes[i]
+	}
+	err := f.copyFile(context.Background(), name, io.MultiWriter(writers...))
+	if err != nil {
+		return "", f.pathError("integrity", err)
+	}
+	values := make([]string, len(algs))
+	for i, alg := range algs {
+		values[i] = alg + "-" + base64.StdEncoding.EncodeToString(hashes[i].Sum(nil))
+	}
+	return strings.Join(values, " "), nil
+}
+
+// AssetInfo describes a file for rendering references to it.
+type AssetInfo struct {
+	Name      string // hashed file name, as returned by Name
+	Integrity string // Subresource Integrity value
+	Size      int64  // size in bytes
+}
+
+// Asset returns the hashed file name, Subresource Integrity value and
+// size of the given file. The name and integrity value are derived from
+// the same cached digest where possible, so the file is read once.
+func (f *FS) Asset(name string) (AssetInfo, error) {
+	_, err := f.HashErr(name)
+	if err != nil {
+		return AssetInfo{}, err
+	}
+	integrity, err := f.integrity(name)
+	if err != nil {
+		return AssetInfo{}, f.pathError("asset", err)
+	}
+	info, err := fs.Stat(f.fs, name)
+	if err != nil {
+		return AssetInfo{}, f.pathError("asset", err)
+	}
+	return AssetInfo{
+		Name:      f.nameOrEmpty(name),
+		Integrity: integrity,
+		Size:      info.Size(),
+	}, nil
+}
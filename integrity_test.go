@@ -0,0 +1,114 @@
+package hashfs
+
+import (
+	"crypto/sha512"
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestIntegrity(t *testing.T) {
+	tests := []struct {
+		h    *FS
+		want string
+	}{
+		{New(testdata), "sha256-1Hb7e+GwLqn2bHl6DBGxH/XbG9cC/2xHIORVowGlAfE="},
+		{New(testdata, WithHashLength(8)), "sha256-1Hb7e+GwLqn2bHl6DBGxH/XbG9cC/2xHIORVowGlAfE="},
+		{New(testdata, WithHasher(sha512.New)), "sha256-1Hb7e+GwLqn2bHl6DBGxH/XbG9cC/2xHIORVowGlAfE="},
+	}
+	for i, tt := range tests {
+		have := tt.h.Integrity("testdata/base.ext")
+		if have != tt.want {
+			t.Errorf("%d. Integrity\nhave '%s'\nwant '%s'", i, have, tt.want)
+		}
+	}
+}
+
+func TestIntegrityCached(t *testing.T) {
+	h := New(testdata)
+	_ = h.LoadManifest(strings.NewReader(`{"testdata/base.ext":"trusted"}`))
+	want := "sha256-1Hb7e+GwLqn2bHl6DBGxH/XbG9cC/2xHIORVowGlAfE="
+	have := h.Integrity("testdata/base.ext")
+	if have != want {
+		t.Errorf("Integrity\nhave '%s'\nwant '%s'", have, want)
+	}
+	if h.Hash("testdata/base.ext") != "trusted" {
+		t.Errorf("Integrity should not replace loaded digests")
+	}
+}
+
+func TestIntegrityPathError(t *testing.T) {
+	h := New(testdata)
+	if h.Integrity("not-found") != "" {
+		t.Errorf("should return an empty string")
+	}
+}
+
+func TestIntegritySet(t *testing.T) {
+	h := New(testdata)
+	tests := []struct {
+		algs []string
+		want string
+	}{
+		{nil, "sha256-1Hb7e+GwLqn2bHl6DBGxH/XbG9cC/2xHIORVowGlAfE="},
+		{[]string{"sha256", "sha512"}, "sha256-1Hb7e+GwLqn2bHl6DBGxH/XbG9cC/2xHIORVowGlAfE= sha512-vgXuz86+at2pNF2IDXbLEVCXHmmJMbggv1P5+ICBo9BJjbGgli3KanDOXRN/kG3oTxcuHmeL3xvzm+mGc/wVxw=="},
+	}
+	for i, tt := range tests {
+		have, err := h.IntegritySet("testdata/base.ext", tt.algs...)
+		if err != nil {
+			t.Fatalf("%d. unexpected error: %v", i, err)
+		}
+		if have != tt.want {
+			t.Errorf("%d. IntegritySet\nhave '%s'\nwant '%s'", i, have, tt.want)
+		}
+	}
+	_, err := h.IntegritySet("testdata/base.ext", "md5")
+	if err == nil {
+		t.Errorf("IntegritySet should error for unknown algorithms")
+	}
+	_, err = h.IntegritySet("not-found")
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("IntegritySet\nhave %v\nwant %v", err, fs.ErrNotExist)
+	}
+}
+
+func TestAsset(t *testing.T) {
+	h := New(testdata)
+	asset, err := h.Asset("testdata/base.ext")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := AssetInfo{
+		Name:      "testdata/base.d476fb7be1b02ea9f66c797a0c11b11ff5db1bd702ff6c4720e455a301a501f1.ext",
+		Integrity: "sha256-1Hb7e+GwLqn2bHl6DBGxH/XbG9cC/2xHIORVowGlAfE=",
+		Size:      9,
+	}
+	if asset != want {
+		t.Errorf("Asset\nhave %+v\nwant %+v", asset, want)
+	}
+	_, err = h.Asset("not-found")
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Asset should yield fs.ErrNotExist, have %v", err)
+	}
+}
+
+func TestAssetNotHashable(t *testing.T) {
+	m := fstest.MapFS{"a.woff": &fstest.MapFile{Data: []byte("a")}}
+	for i, opt := range []Option{WithExtensions(".js"), WithDisabled(true)} {
+		asset, err := New(m, opt).Asset("a.woff")
+		if err != nil {
+			t.Fatalf("%d. unexpected error: %v", i, err)
+		}
+		if asset.Name != "a.woff" {
+			t.Errorf("%d. Asset name\nhave '%s'\nwant '%s'", i, asset.Name, "a.woff")
+		}
+		f, err := New(m, opt).Open(asset.Name)
+		if err != nil {
+			t.Errorf("%d. unexpected error: %v", i, err)
+			continue
+		}
+		f.Close()
+	}
+}
@@ -0,0 +1,124 @@
+package hashfs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"strings"
+)
+
+// Manifest returns a copy of the mapping of original file
+// names to hashed file names for all files hashed so far.
+// Files that are not hashable are left out.
+func (f *FS) Manifest() map[string]string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	m := make(map[string]string, len(f.hash))
+	for name, hash := range f.hash {
+		if !f.hashable(name) {
+			continue
+		}
+		m[name] = f.format(name, hash)
+	}
+	return m
+}
+
+// WriteManifest writes the manifest to w as JSON keyed by original file name.
+func (f *FS) WriteManifest(w io.Writer) error {
+	return json.NewEncoder(w).Encode(f.Manifest())
+}
+
+// PrintManifest writes the manifest to w in the given format, either
+// "json" as in WriteManifest or "text" with one "original -> hashed"
+// line per file, sorted by original file name.
+func (f *FS) PrintManifest(w io.Writer, format string) error {
+	switch format {
+	case "json":
+		return f.WriteManifest(w)
+	case "text":
+		m := f.Manifest()
+		names := make([]string, 0, len(m))
+		for name := range m {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			_, err := fmt.Fprintf(w, "%s -> %s\n", name, m[name])
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("hashfs: unknown manifest format %q", format)
+}
+
+// LoadManifest reads a JSON mapping of original file names to digests
+// or, as written by WriteManifest, to hashed file names from r and
+// populates the cache without reading file contents. Loaded entries
+// are trusted and not verified against file contents.
+func (f *FS) LoadManifest(r io.Reader) error {
+	var m map[string]string
+	err := json.NewDecoder(r).Decode(&m)
+	if err != nil {
+		return err
+	}
+	for name, hash := range m {
+		base, digest, ok := f.parse(hash)
+		if ok && base == name {
+			hash = digest
+		}
+		err = f.register(name, hash)
+		if err != nil {
+			return &fs.PathError{Op: "loadmanifest", Path: name, Err: err}
+		}
+	}
+	return nil
+}
+
+// Register populates the cache with the given digest of the named file
+// without reading its contents, such as a digest computed by a build
+// tool. Registered digests are trusted and not verified against file
+// contents. Digests with a hashed file name that already resolves to
+// another file are ignored.
+func (f *FS) Register(name, digest string) {
+	f.register(name, digest)
+}
+
+// register is like Register but returns ErrCollision if the
+// hashed file name already resolves to another file.
+func (f *FS) register(name, digest string) error {
+	f.mu.Lock()
+	delete(f.mtime, name)
+	f.mu.Unlock()
+	return f.setHash(name, digest, nil)
+}
+
+// Cached returns the sorted original file names hashed so far.
+func (f *FS) Cached() []string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	names := make([]string, 0, len(f.hash))
+	for name := range f.hash {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// FindByPrefix returns the sorted original file names hashed so far
+// that start with the given prefix, such as a directory "css/".
+func (f *FS) FindByPrefix(prefix string) []string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	var names []string
+	for name := range f.hash {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
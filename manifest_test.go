@@ -0,0 +1,205 @@
+package hashfs
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestManifest(t *testing.T) {
+	h := New(testdata)
+	h.Hash("testdata/base.ext")
+	h.Hash("testdata/noext")
+	m := h.Manifest()
+	want := map[string]string{
+		"testdata/base.ext": "testdata/base.d476fb7be1b02ea9f66c797a0c11b11ff5db1bd702ff6c4720e455a301a501f1.ext",
+		"testdata/noext":    "testdata/noext.d9d4e730296e72377ae86529027f7defd5feecf4602a1f15f561cd4fae3644c5",
+	}
+	if len(m) != len(want) {
+		t.Fatalf("Manifest()\nhave %v\nwant %v", m, want)
+	}
+	for k, v := range want {
+		if m[k] != v {
+			t.Errorf("Manifest()[%q]\nhave '%s'\nwant '%s'", k, m[k], v)
+		}
+	}
+	m["testdata/base.ext"] = "mutated"
+	if h.Name("testdata/base.ext") != want["testdata/base.ext"] {
+		t.Errorf("Manifest should return a copy")
+	}
+}
+
+func TestManifestNotHashable(t *testing.T) {
+	m := fstest.MapFS{
+		"app.js":    &fstest.MapFile{Data: []byte("a")},
+		"font.woff": &fstest.MapFile{Data: []byte("b")},
+	}
+	tests := []struct {
+		opts []Option
+		want map[string]string
+	}{
+		{[]Option{WithExtensions(".js")}, map[string]string{"app.js": "app.ca978112.js"}},
+		{[]Option{WithDisabled(true)}, map[string]string{}},
+	}
+	for i, tt := range tests {
+		h := New(m, append(tt.opts, WithHashLength(8))...)
+		h.Hash("app.js")
+		h.Hash("font.woff")
+		have := h.Manifest()
+		if len(have) != len(tt.want) {
+			t.Errorf("%d. Manifest()\nhave %v\nwant %v", i, have, tt.want)
+			continue
+		}
+		for k, v := range tt.want {
+			if have[k] != v {
+				t.Errorf("%d. Manifest()[%q]\nhave '%s'\nwant '%s'", i, k, have[k], v)
+			}
+		}
+	}
+}
+
+func TestWriteManifest(t *testing.T) {
+	h := New(testdata)
+	h.Hash("testdata/base.ext")
+	var buf bytes.Buffer
+	err := h.WriteManifest(&buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `{"testdata/base.ext":"testdata/base.d476fb7be1b02ea9f66c797a0c11b11ff5db1bd702ff6c4720e455a301a501f1.ext"}` + "\n"
+	if buf.String() != want {
+		t.Errorf("WriteManifest\nhave '%s'\nwant '%s'", buf.String(), want)
+	}
+}
+
+func TestLoadManifest(t *testing.T) {
+	h := New(testdata)
+	r := strings.NewReader(`{"testdata/base.ext":"trusted"}`)
+	err := h.LoadManifest(r)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	name := h.Name("testdata/base.ext")
+	want := "testdata/base.trusted.ext"
+	if name != want {
+		t.Errorf("Name\nhave '%s'\nwant '%s'", name, want)
+	}
+	f, err := h.Open(want)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = f.Close()
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestLoadManifestRoundTrip(t *testing.T) {
+	h := New(testdata, WithHashLength(8))
+	h.Hash("testdata/base.ext")
+	h.Hash("testdata/noext")
+	var buf bytes.Buffer
+	err := h.WriteManifest(&buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	g := New(testdata, WithHashLength(8))
+	err = g.LoadManifest(&buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, name := range []string{"testdata/base.ext", "testdata/noext"} {
+		if g.Name(name) != h.Name(name) {
+			t.Errorf("Name(%q) after LoadManifest\nhave '%s'\nwant '%s'", name, g.Name(name), h.Name(name))
+		}
+	}
+}
+
+func TestLoadManifestError(t *testing.T) {
+	h := New(testdata)
+	err := h.LoadManifest(strings.NewReader("not json"))
+	if err == nil {
+		t.Errorf("LoadManifest should error")
+	}
+}
+
+func TestCached(t *testing.T) {
+	h := New(testdata)
+	h.Hash("testdata/noext")
+	h.Hash("testdata/base.ext")
+	h.Hash("testdata/missing.ext")
+	have := h.Cached()
+	want := []string{"testdata/base.ext", "testdata/noext"}
+	if strings.Join(have, ",") != strings.Join(want, ",") {
+		t.Errorf("Cached()\nhave %v\nwant %v", have, want)
+	}
+}
+
+func TestFindByPrefix(t *testing.T) {
+	h := New(testdata)
+	h.Register("css/a.css", "a")
+	h.Register("css/b.css", "b")
+	h.Register("cssx/c.css", "c")
+	h.Register("js/a.js", "a")
+	tests := []struct {
+		prefix string
+		want   []string
+	}{
+		{"css/", []string{"css/a.css", "css/b.css"}},
+		{"css", []string{"css/a.css", "css/b.css", "cssx/c.css"}},
+		{"img/", nil},
+	}
+	for _, tt := range tests {
+		have := h.FindByPrefix(tt.prefix)
+		if strings.Join(have, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("FindByPrefix(%q)\nhave %v\nwant %v", tt.prefix, have, tt.want)
+		}
+	}
+}
+
+func TestRegister(t *testing.T) {
+	h := New(testdata)
+	h.Hash("testdata/base.ext")
+	h.Register("testdata/base.ext", "trusted")
+	want := "testdata/base.trusted.ext"
+	if h.Name("testdata/base.ext") != want {
+		t.Errorf("Name\nhave '%s'\nwant '%s'", h.Name("testdata/base.ext"), want)
+	}
+	base, ok := h.Base(want)
+	if !ok || base != "testdata/base.ext" {
+		t.Errorf("Base(%q)\nhave '%s'\nwant '%s'", want, base, "testdata/base.ext")
+	}
+	_, ok = h.getBase("testdata/base.d476fb7be1b02ea9f66c797a0c11b11ff5db1bd702ff6c4720e455a301a501f1.ext")
+	if ok {
+		t.Errorf("Register should replace the previous hashed name")
+	}
+}
+
+func TestPrintManifest(t *testing.T) {
+	h := New(testdata, WithHashLength(8))
+	h.Hash("testdata/noext")
+	h.Hash("testdata/base.ext")
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"json", `{"testdata/base.ext":"testdata/base.d476fb7b.ext","testdata/noext":"testdata/noext.d9d4e730"}` + "\n"},
+		{"text", "testdata/base.ext -> testdata/base.d476fb7b.ext\ntestdata/noext -> testdata/noext.d9d4e730\n"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		err := h.PrintManifest(&buf, tt.format)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if buf.String() != tt.want {
+			t.Errorf("PrintManifest(%q)\nhave %q\nwant %q", tt.format, buf.String(), tt.want)
+		}
+	}
+	err := h.PrintManifest(io.Discard, "yaml")
+	if err == nil {
+		t.Errorf("PrintManifest should error on unknown formats")
+	}
+}
@@ -0,0 +1,48 @@
+package hashfs
+
+import (
+	"io/fs"
+)
+
+// WithModTimeCheck checks the modification time of a file on every cache
+// hit, such as in Hash and Open, and recomputes its digest if the file has
+// changed since it was hashed. This is useful for development servers over
+// a mutable file system such as os.DirFS. Digests loaded from a manifest
+// are not checked.
+func WithModTimeCheck(enabled bool) Option {
+	return func(f *FS) {
+		f.modcheck = enabled
+	}
+}
+
+// setModTime records the current modification time of the given file.
+func (f *FS) setModTime(name string) error {
+	info, err := fs.Stat(f.fs, name)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.mtime[name] = info.ModTime()
+	return nil
+}
+
+// stale reports whether the given file has changed since it was hashed,
+// invalidating its cached digest if so.
+func (f *FS) stale(name string) bool {
+	if !f.modcheck {
+		return false
+	}
+	f.mu.RLock()
+	mtime, ok := f.mtime[name]
+	f.mu.RUnlock()
+	if !ok {
+		return false
+	}
+	info, err := fs.Stat(f.fs, name)
+	if err == nil && info.ModTime().Equal(mtime) {
+		return false
+	}
+	f.Invalidate(name)
+	return true
+}
@@ -0,0 +1,30 @@
+package hashfs
+
+import (
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestWithModTimeCheck(t *testing.T) {
+	m := fstest.MapFS{"a.txt": &fstest.MapFile{Data: []byte("a"), ModTime: time.Unix(1, 0)}}
+	h := New(m, WithModTimeCheck(true))
+	const a = "a.ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb.txt"
+	const b = "a.3e23e8160039594a33894f6564e1b1348bbd7a0088d42c4acb73eeaed59c009d.txt"
+	if h.Name("a.txt") != a {
+		t.Fatalf("Name\nhave '%s'\nwant '%s'", h.Name("a.txt"), a)
+	}
+	m["a.txt"] = &fstest.MapFile{Data: []byte("b"), ModTime: time.Unix(2, 0)}
+	_, err := h.Open(a)
+	if err == nil {
+		t.Errorf("Open(%q) should error once the file changed", a)
+	}
+	if h.Name("a.txt") != b {
+		t.Errorf("Name\nhave '%s'\nwant '%s'", h.Name("a.txt"), b)
+	}
+	f, err := h.Open(b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	f.Close()
+}
@@ -0,0 +1,257 @@
+package hashfs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Merge returns a FS over the given file systems mounted at the given
+// directories, such as {"css": cssFS, "js": jsFS}. Names are routed to
+// the file system with the longest matching directory, with "" or "."
+// mounting a file system at the root. Directories containing mounts
+// list them along with their own files. Panics if a directory is not
+// a valid fs.FS path.
+func Merge(mounts map[string]fs.FS, opts ...Option) *FS {
+	m := mountFS{mounts: make(map[string]fs.FS, len(mounts))}
+	for dir, fsys := range mounts {
+		dir = strings.Trim(dir, "/")
+		if dir == "" {
+			dir = "."
+		}
+		if !fs.ValidPath(dir) {
+			panic("hashfs: invalid mount " + dir)
+		}
+		_, ok := m.mounts[dir]
+		if !ok {
+			m.dirs = append(m.dirs, dir)
+		}
+		m.mounts[dir] = fsys
+	}
+	// Longest first for routing, with the root last.
+	sort.Slice(m.dirs, func(i, j int) bool {
+		a, b := m.dirs[i], m.dirs[j]
+		if a == "." || b == "." {
+			return a != "."
+		}
+		if len(a) != len(b) {
+			return len(a) > len(b)
+		}
+		return a < b
+	})
+	return New(m, opts...)
+}
+
+// mountFS is a fs.FS dispatching to file systems by directory.
+type mountFS struct {
+	mounts map[string]fs.FS
+	dirs   []string // longest first
+}
+
+// route returns the file system and its name for the given name.
+func (m mountFS) route(name string) (fs.FS, string, bool) {
+	for _, dir := range m.dirs {
+		if dir == "." {
+			return m.mounts[dir], name, true
+		}
+		if name == dir {
+			return m.mounts[dir], ".", true
+		}
+		if strings.HasPrefix(name, dir+"/") {
+			return m.mounts[dir], name[len(dir)+1:], true
+		}
+	}
+	return nil, "", false
+}
+
+// children returns the entries of the mounts below the given directory.
+func (m mountFS) children(name string) []fs.DirEntry {
+	seen := make(map[string]bool)
+	var entries []fs.DirEntry
+	for _, dir := range m.dirs {
+		rel := dir
+		if name != "." {
+			if !strings.HasPrefix(dir, name+"/") {
+				continue
+			}
+			rel = dir[len(name)+1:]
+		} else if dir == "." {
+			continue
+		}
+		child := rel
+		i := strings.IndexByte(rel, '/')
+		if i >= 0 {
+			child = rel[:i]
+		}
+		if !seen[child] {
+			seen[child] = true
+			entries = append(entries, mountInfo{name: child})
+		}
+	}
+	return entries
+}
+
+// virtual reports whether the given name is a mounted directory
+// or a directory containing mounts.
+func (m mountFS) virtual(name string) bool {
+	_, ok := m.mounts[name]
+	return ok || len(m.children(name)) > 0
+}
+
+// Open implements the fs.FS interface.
+func (m mountFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if m.virtual(name) {
+		entries, err := m.ReadDir(name)
+		if err != nil {
+			return nil, err
+		}
+		return &mountDir{info: mountInfo{name: pathBase(name)}, entries: entries}, nil
+	}
+	fsys, rel, ok := m.route(name)
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return fsys.Open(rel)
+}
+
+// Stat implements the fs.StatFS interface.
+func (m mountFS) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+	if m.virtual(name) {
+		return mountInfo{name: pathBase(name)}, nil
+	}
+	fsys, rel, ok := m.route(name)
+	if !ok {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	return fs.Stat(fsys, rel)
+}
+
+// ReadDir implements the fs.ReadDirFS interface.
+func (m mountFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	entries := m.children(name)
+	fsys, rel, ok := m.route(name)
+	if ok {
+		own, err := fs.ReadDir(fsys, rel)
+		if err != nil && (len(entries) == 0 || !errors.Is(err, fs.ErrNotExist)) {
+			return nil, err
+		}
+		seen := make(map[string]bool, len(entries))
+		for _, entry := range entries {
+			seen[entry.Name()] = true
+		}
+		for _, entry := range own {
+			if !seen[entry.Name()] {
+				entries = append(entries, entry)
+			}
+		}
+	} else if len(entries) == 0 {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, nil
+}
+
+// pathBase returns the last element of the given valid path.
+func pathBase(name string) string {
+	return name[strings.LastIndexByte(name, '/')+1:]
+}
+
+// mountInfo is the fs.FileInfo and fs.DirEntry of
+// a mounted directory or a directory containing mounts.
+type mountInfo struct {
+	name string
+}
+
+// Name implements the fs.FileInfo interface.
+func (fi mountInfo) Name() string {
+	return fi.name
+}
+
+// Size implements the fs.FileInfo interface.
+func (fi mountInfo) Size() int64 {
+	return 0
+}
+
+// Mode implements the fs.FileInfo interface.
+func (fi mountInfo) Mode() fs.FileMode {
+	return fs.ModeDir | 0555
+}
+
+// ModTime implements the fs.FileInfo interface.
+func (fi mountInfo) ModTime() time.Time {
+	return time.Time{}
+}
+
+// IsDir implements the fs.FileInfo interface.
+func (fi mountInfo) IsDir() bool {
+	return true
+}
+
+// Sys implements the fs.FileInfo interface.
+func (fi mountInfo) Sys() interface{} {
+	return nil
+}
+
+// Type implements the fs.DirEntry interface.
+func (fi mountInfo) Type() fs.FileMode {
+	return fs.ModeDir
+}
+
+// Info implements the fs.DirEntry interface.
+func (fi mountInfo) Info() (fs.FileInfo, error) {
+	return fi, nil
+}
+
+// mountDir is a fs.ReadDirFile of a mounted
+// directory or a directory containing mounts.
+type mountDir struct {
+	info    mountInfo
+	entries []fs.DirEntry
+}
+
+// Stat implements the fs.File interface.
+func (d *mountDir) Stat() (fs.FileInfo, error) {
+	return d.info, nil
+}
+
+// Read implements the fs.File interface.
+func (d *mountDir) Read(p []byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: errors.New("is a directory")}
+}
+
+// Close implements the fs.File interface.
+func (d *mountDir) Close() error {
+	return nil
+}
+
+// ReadDir implements the fs.ReadDirFile interface.
+func (d *mountDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if n <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(d.entries) {
+		n = len(d.entries)
+	}
+	entries := d.entries[:n]
+	d.entries = d.entries[n:]
+	return entries, nil
+}
@@ -0,0 +1,78 @@
+package hashfs
+
+import (
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestMerge(t *testing.T) {
+	mounts := map[string]fs.FS{
+		"/static/css/": fstest.MapFS{"a.css": &fstest.MapFile{Data: []byte("a")}},
+		"static/js":    fstest.MapFS{"a.js": &fstest.MapFile{Data: []byte("b")}},
+		"": fstest.MapFS{
+			"favicon.ico":      &fstest.MapFile{Data: []byte("c")},
+			"static/js/old.js": &fstest.MapFile{Data: []byte("c")},
+		},
+	}
+	h := Merge(mounts, WithHashLength(8))
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"static/css/a.css", "static/css/a.ca978112.css"},
+		{"static/js/a.js", "static/js/a.3e23e816.js"},
+		{"favicon.ico", "favicon.2e7d2c03.ico"},
+		{"static/js/old.js", ""},
+	}
+	for _, tt := range tests {
+		have := h.Name(tt.name)
+		if have != tt.want {
+			t.Errorf("Name(%q)\nhave '%s'\nwant '%s'", tt.name, have, tt.want)
+		}
+	}
+	f, err := h.Open("static/js/a.3e23e816.js")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, _ := io.ReadAll(f)
+	f.Close()
+	if string(b) != "b" {
+		t.Errorf("Open\nhave %q\nwant %q", b, "b")
+	}
+	var names []string
+	err = h.WalkHashed(func(hashedPath string, d fs.DirEntry) error {
+		names = append(names, hashedPath)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "favicon.2e7d2c03.ico,static/css/a.ca978112.css,static/js/a.3e23e816.js"
+	if strings.Join(names, ",") != want {
+		t.Errorf("WalkHashed\nhave %v\nwant %s", names, want)
+	}
+	err = fstest.TestFS(h.fs, "favicon.ico", "static/css/a.css", "static/js/a.js")
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMergeRootLast(t *testing.T) {
+	mounts := map[string]fs.FS{
+		"":  fstest.MapFS{"b.js": &fstest.MapFile{Data: []byte("b")}},
+		"a": fstest.MapFS{"x.js": &fstest.MapFile{Data: []byte("a")}},
+		"c": fstest.MapFS{"x.js": &fstest.MapFile{Data: []byte("c")}},
+	}
+	for i := 0; i < 100; i++ {
+		h := Merge(mounts)
+		for _, name := range []string{"a/x.js", "c/x.js", "b.js"} {
+			_, err := h.ReadFile(name)
+			if err != nil {
+				t.Fatalf("%d. unexpected error: %v", i, err)
+			}
+		}
+	}
+}
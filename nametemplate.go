@@ -0,0 +1,111 @@
+package hashfs
+
+import (
+	"hash"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// WithNameTemplate sets the template of hashed file names, overriding
+// the format and separator. The placeholders "{base}", "{hash}" and
+// "{ext}" are replaced with the file name without its extension, the
+// digest and the extension including its leading dot respectively. If
+// "{ext}" is omitted, "{base}" is the whole file name. Directories are
+// kept as is. For example, "{hash}-{base}{ext}" formats "js/app.js" as
+// "js/<hash>-app.js". Panics if the template does not contain "{base}"
+// and "{hash}" exactly once, repeats "{ext}" or contains a slash.
+func WithNameTemplate(tmpl string) Option {
+	if strings.Count(tmpl, "{base}") != 1 ||
+		strings.Count(tmpl, "{hash}") != 1 ||
+		strings.Count(tmpl, "{ext}") > 1 ||
+		strings.Contains(tmpl, "/") {
+		panic("hashfs: invalid name template")
+	}
+	return func(f *FS) {
+		f.tmpl = tmpl
+	}
+}
+
+// placeholder matches the placeholders of a name template.
+var placeholder = regexp.MustCompile(`\{(base|hash|ext)\}`)
+
+// templateRegexp returns the expression matching file names
+// formatted by the name template.
+func (f *FS) templateRegexp() *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^")
+	last := 0
+	for _, m := range placeholder.FindAllStringSubmatchIndex(f.tmpl, -1) {
+		b.WriteString(regexp.QuoteMeta(f.tmpl[last:m[0]]))
+		switch f.tmpl[m[2]:m[3]] {
+		case "base":
+			b.WriteString(`(?P<base>.+?)`)
+		case "hash":
+			b.WriteString(`(?P<hash>` + f.hashPattern() + `)`)
+		case "ext":
+			n := 1
+			if f.extSegments > 1 {
+				n = f.extSegments
+			}
+			b.WriteString(`(?P<ext>(?:\.[^.]*){1,` + strconv.Itoa(n) + `})?`)
+		}
+		last = m[1]
+	}
+	b.WriteString(regexp.QuoteMeta(f.tmpl[last:]))
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
+// hashPattern returns the expression matching encoded digests.
+func (f *FS) hashPattern() string {
+	if f.impl != nil {
+		// Encoding is entirely up to the Hasher.
+		return `.+?`
+	}
+	alphabet := f.enc.alphabet()
+	if f.fold && f.enc == Hex {
+		alphabet += "ABCDEF"
+	}
+	class := "[" + regexp.QuoteMeta(alphabet) + "]"
+	if f.adaptive {
+		return class + "{" + strconv.Itoa(f.length) + ",}"
+	}
+	if f.length > 0 {
+		return class + "{" + strconv.Itoa(f.length) + "}"
+	}
+	var alts []string
+	for _, fn := range append([]func() hash.Hash{f.newHash}, f.alt...) {
+		alts = append(alts, class+"{"+strconv.Itoa(f.enc.encodedLen(fn().Size()))+"}")
+	}
+	return "(?:" + strings.Join(alts, "|") + ")"
+}
+
+// formatTemplate returns the hashed file name using the name template.
+func (f *FS) formatTemplate(name, hash string) string {
+	i := strings.LastIndexByte(name, '/') + 1
+	dir, file := name[:i], name[i:]
+	ext := ""
+	if strings.Contains(f.tmpl, "{ext}") {
+		ext = f.ext(file)
+	}
+	r := strings.NewReplacer("{base}", file[:len(file)-len(ext)], "{hash}", hash, "{ext}", ext)
+	return dir + r.Replace(f.tmpl)
+}
+
+// parseTemplate returns the base name and digest
+// of the given name using the name template.
+func (f *FS) parseTemplate(name string) (string, string, bool) {
+	i := strings.LastIndexByte(name, '/') + 1
+	dir, file := name[:i], name[i:]
+	m := f.tmplRe.FindStringSubmatch(file)
+	if m == nil {
+		// Needs to match the template to be a request for a hashed file.
+		return "", "", false
+	}
+	base := m[f.tmplRe.SubexpIndex("base")]
+	if j := f.tmplRe.SubexpIndex("ext"); j >= 0 {
+		base += m[j]
+	}
+	return dir + base, m[f.tmplRe.SubexpIndex("hash")], true
+}
@@ -0,0 +1,76 @@
+package hashfs
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"testing"
+	"testing/fstest"
+)
+
+func TestWithNameTemplate(t *testing.T) {
+	m := fstest.MapFS{
+		"js/app.js":     &fstest.MapFile{Data: []byte("a")},
+		"js/app.min.js": &fstest.MapFile{Data: []byte("b")},
+		"noext":         &fstest.MapFile{Data: []byte("c")},
+	}
+	tests := []struct {
+		tmpl string
+		name string
+		want string
+	}{
+		{"{base}.v{hash}{ext}", "js/app.js", "js/app.vca978112.js"},
+		{"{base}-{hash}.min{ext}", "js/app.js", "js/app-ca978112.min.js"},
+		{"{hash}-{base}{ext}", "js/app.js", "js/ca978112-app.js"},
+		{"{hash}-{base}{ext}", "js/app.min.js", "js/3e23e816-app.min.js"},
+		{"{hash}-{base}{ext}", "noext", "2e7d2c03-noext"},
+		{"{base}_{hash}", "js/app.js", "js/app.js_ca978112"},
+	}
+	for _, tt := range tests {
+		h := New(m, WithNameTemplate(tt.tmpl), WithHashLength(8))
+		have := h.Name(tt.name)
+		if have != tt.want {
+			t.Errorf("%s Name(%q)\nhave '%s'\nwant '%s'", tt.tmpl, tt.name, have, tt.want)
+			continue
+		}
+		g := New(m, WithNameTemplate(tt.tmpl), WithHashLength(8))
+		base, ok := g.Base(have)
+		if !ok || base != tt.name {
+			t.Errorf("%s Base(%q)\nhave '%s'\nwant '%s'", tt.tmpl, have, base, tt.name)
+		}
+	}
+}
+
+func TestWithNameTemplateHashers(t *testing.T) {
+	m := fstest.MapFS{"app.js": &fstest.MapFile{Data: []byte("a")}}
+	h := New(m, WithNameTemplate("{hash}-{base}{ext}"), WithHasher(sha512.New))
+	g := New(m, WithNameTemplate("{hash}-{base}{ext}"), WithHashers(sha256.New, sha512.New))
+	name := h.Name("app.js")
+	_, ok := g.Base(name)
+	if !ok {
+		t.Errorf("Base(%q) should resolve with an alternate hasher", name)
+	}
+	_, ok = g.Base("ca978112-app.js")
+	if ok {
+		t.Errorf("Base should not resolve truncated digests")
+	}
+}
+
+func TestWithNameTemplateInvalid(t *testing.T) {
+	tests := []string{
+		"{base}{ext}",
+		"{hash}{ext}",
+		"{base}.{hash}.{hash}{ext}",
+		"{base}{ext}.{hash}{ext}",
+		"v/{base}.{hash}{ext}",
+	}
+	for _, tt := range tests {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("WithNameTemplate(%q) should panic", tt)
+				}
+			}()
+			WithNameTemplate(tt)
+		}()
+	}
+}
@@ -0,0 +1,40 @@
+package hashfs
+
+import (
+	"time"
+)
+
+// Observer is notified of cache activity, such as for metrics.
+// Implementations must be safe for concurrent use.
+type Observer interface {
+	// HashComputed is called after the digest of the named
+	// file is computed, with the time spent computing it.
+	HashComputed(name string, dur time.Duration)
+
+	// CacheHit is called when a file name is resolved
+	// from the cache without computing its digest.
+	CacheHit(name string)
+}
+
+// SkipObserver is an Observer that is also notified of the files
+// skipped by WarmCache, such as broken symbolic links.
+type SkipObserver interface {
+	Observer
+
+	// Skipped is called when the named file is skipped
+	// by WarmCache, with the reason it was skipped.
+	Skipped(name string, err error)
+}
+
+// WithObserver sets the Observer notified of cache activity.
+func WithObserver(obs Observer) Option {
+	return func(f *FS) {
+		f.obs = obs
+	}
+}
+
+// logger is notified of events worth logging. See WithLogger.
+type logger interface {
+	hashComputed(name string, size int64, dur time.Duration)
+	hashMismatch(name string)
+}
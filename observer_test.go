@@ -0,0 +1,52 @@
+package hashfs
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// testObserver records cache activity.
+type testObserver struct {
+	mu       sync.Mutex
+	computed []string
+	hits     []string
+}
+
+// HashComputed implements the Observer interface.
+func (o *testObserver) HashComputed(name string, dur time.Duration) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.computed = append(o.computed, name)
+}
+
+// CacheHit implements the Observer interface.
+func (o *testObserver) CacheHit(name string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.hits = append(o.hits, name)
+}
+
+func TestObserver(t *testing.T) {
+	obs := &testObserver{}
+	h := New(testdata, WithObserver(obs))
+	name := h.Name("testdata/base.ext")
+	h.Hash("testdata/base.ext")
+	f, err := h.Open(name)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	f.Close()
+	if len(obs.computed) != 1 || obs.computed[0] != "testdata/base.ext" {
+		t.Errorf("HashComputed\nhave %v", obs.computed)
+	}
+	want := []string{"testdata/base.ext", name}
+	if len(obs.hits) != len(want) {
+		t.Fatalf("CacheHit\nhave %v\nwant %v", obs.hits, want)
+	}
+	for i := range want {
+		if obs.hits[i] != want[i] {
+			t.Errorf("CacheHit[%d]\nhave '%s'\nwant '%s'", i, obs.hits[i], want[i])
+		}
+	}
+}
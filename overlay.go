@@ -0,0 +1,68 @@
+package hashfs
+
+import (
+	"errors"
+	"io/fs"
+	"sort"
+)
+
+// WithOverlay layers the given file system over the underlying file
+// system. Files in the overlay take precedence over files of the same
+// name in the underlying file system, and directories are merged.
+func WithOverlay(overlay fs.FS) Option {
+	return func(f *FS) {
+		f.fs = overlayFS{top: overlay, fs: f.fs}
+	}
+}
+
+// overlayFS is a fs.FS that prefers files from top.
+type overlayFS struct {
+	top fs.FS
+	fs  fs.FS
+}
+
+// Open implements the fs.FS interface.
+func (o overlayFS) Open(name string) (fs.File, error) {
+	f, err := o.top.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return o.fs.Open(name)
+	}
+	return f, err
+}
+
+// Stat implements the fs.StatFS interface.
+func (o overlayFS) Stat(name string) (fs.FileInfo, error) {
+	info, err := fs.Stat(o.top, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fs.Stat(o.fs, name)
+	}
+	return info, err
+}
+
+// ReadDir implements the fs.ReadDirFS interface.
+func (o overlayFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	top, err := fs.ReadDir(o.top, name)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	entries, err := fs.ReadDir(o.fs, name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) && top != nil {
+			return top, nil
+		}
+		return nil, err
+	}
+	seen := make(map[string]bool, len(top))
+	for _, entry := range top {
+		seen[entry.Name()] = true
+	}
+	for _, entry := range entries {
+		if !seen[entry.Name()] {
+			top = append(top, entry)
+		}
+	}
+	sort.Slice(top, func(i, j int) bool {
+		return top[i].Name() < top[j].Name()
+	})
+	return top, nil
+}
@@ -0,0 +1,65 @@
+package hashfs
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func TestOverlay(t *testing.T) {
+	base := fstest.MapFS{
+		"a.txt":     &fstest.MapFile{Data: []byte("a")},
+		"dir/b.txt": &fstest.MapFile{Data: []byte("b")},
+	}
+	overlay := fstest.MapFS{
+		"a.txt":     &fstest.MapFile{Data: []byte("b")},
+		"dir/c.txt": &fstest.MapFile{Data: []byte("c")},
+	}
+	h := New(base, WithOverlay(overlay), WithHashLength(8))
+	tests := []struct {
+		name   string
+		hashed string
+		want   string
+	}{
+		{"a.txt", "a.3e23e816.txt", "b"},
+		{"dir/b.txt", "dir/b.3e23e816.txt", "b"},
+		{"dir/c.txt", "dir/c.2e7d2c03.txt", "c"},
+	}
+	for _, tt := range tests {
+		hashed := h.Name(tt.name)
+		if hashed != tt.hashed {
+			t.Errorf("Name(%q)\nhave '%s'\nwant '%s'", tt.name, hashed, tt.hashed)
+			continue
+		}
+		b, err := fs.ReadFile(h, hashed)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			continue
+		}
+		if string(b) != tt.want {
+			t.Errorf("ReadFile(%q)\nhave %q\nwant %q", hashed, b, tt.want)
+		}
+	}
+	h = New(base, WithOverlay(overlay))
+	err := h.WarmCache()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(h.hash) != 3 {
+		t.Errorf("WarmCache should walk both file systems, have %v", h.hash)
+	}
+}
+
+func TestOverlaySub(t *testing.T) {
+	base := fstest.MapFS{"dir/b.txt": &fstest.MapFile{Data: []byte("a")}}
+	overlay := fstest.MapFS{"dir/b.txt": &fstest.MapFile{Data: []byte("b")}}
+	h := New(base, WithOverlay(overlay), WithHashLength(8))
+	sub, err := h.Sub("dir")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	name := sub.(*FS).Name("b.txt")
+	if name != "b.3e23e816.txt" {
+		t.Errorf("Name\nhave '%s'\nwant '%s'", name, "b.3e23e816.txt")
+	}
+}
@@ -0,0 +1,81 @@
+package hashfs
+
+import (
+	"io/fs"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// StartPolling re-stats the cached files every interval, invalidating
+// any whose modification time changed or that were removed, so that the
+// next lookup recomputes their digests. It is a lightweight alternative
+// to watching the file system for development servers. Call stop to end
+// polling; stop waits for an ongoing poll to finish.
+func (f *FS) StartPolling(interval time.Duration) (stop func()) {
+	atomic.AddInt32(&f.polling, 1)
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		f.poll()
+		for {
+			select {
+			case <-ticker.C:
+				f.poll()
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			<-exited
+			atomic.AddInt32(&f.polling, -1)
+		})
+	}
+}
+
+// isPolling reports whether StartPolling is active.
+func (f *FS) isPolling() bool {
+	return atomic.LoadInt32(&f.polling) > 0
+}
+
+// poll invalidates the cached files that changed since they were
+// hashed. Files without a recorded modification time, such as those
+// hashed before polling started, are recorded for the next poll.
+func (f *FS) poll() {
+	f.mu.RLock()
+	mtimes := make(map[string]time.Time, len(f.hash))
+	names := make([]string, 0, len(f.hash))
+	for name := range f.hash {
+		names = append(names, name)
+		mtime, ok := f.mtime[name]
+		if ok {
+			mtimes[name] = mtime
+		}
+	}
+	f.mu.RUnlock()
+	for _, name := range names {
+		mtime, ok := mtimes[name]
+		info, err := fs.Stat(f.fs, name)
+		if !ok {
+			if err == nil {
+				f.mu.Lock()
+				_, cached := f.hash[name]
+				if cached {
+					f.mtime[name] = info.ModTime()
+				}
+				f.mu.Unlock()
+			}
+			continue
+		}
+		if err != nil || !info.ModTime().Equal(mtime) {
+			f.Invalidate(name)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package hashfs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStartPolling(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "a.txt")
+	err := os.WriteFile(name, []byte("a"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewFromDir(dir, WithHashLength(8))
+	if h.Name("a.txt") != "a.ca978112.txt" {
+		t.Fatalf("Name\nhave '%s'\nwant '%s'", h.Name("a.txt"), "a.ca978112.txt")
+	}
+	stop := h.StartPolling(time.Millisecond)
+	defer stop()
+	time.Sleep(10 * time.Millisecond)
+	err = os.WriteFile(name, []byte("b"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Chtimes(name, time.Unix(1, 0), time.Unix(1, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "a.3e23e816.txt"
+	deadline := time.Now().Add(time.Second)
+	for h.Name("a.txt") != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("Name\nhave '%s'\nwant '%s'", h.Name("a.txt"), want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	stop()
+	stop()
+	if h.isPolling() {
+		t.Errorf("stop should end polling")
+	}
+}
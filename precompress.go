@@ -0,0 +1,138 @@
+package hashfs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"mime"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// compressor represents a content encoding used to precompress files.
+type compressor struct {
+	encoding string // Content-Encoding token
+	compress func(w io.Writer) (io.WriteCloser, error)
+}
+
+// WithPrecompress compresses text assets with gzip at the given level
+// as they are hashed by WarmCache. FileServer serves the compressed
+// variant to clients that accept it. Digests always reflect the
+// uncompressed contents. Panics if level is not a valid gzip level.
+func WithPrecompress(level int) Option {
+	if level < gzip.HuffmanOnly || level > gzip.BestCompression {
+		panic("hashfs: invalid gzip compression level")
+	}
+	return withCompressor(compressor{
+		encoding: "gzip",
+		compress: func(w io.Writer) (io.WriteCloser, error) {
+			return gzip.NewWriterLevel(w, level)
+		},
+	})
+}
+
+// withCompressor adds a precompression content encoding.
+// Brotli is preferred over other content encodings.
+func withCompressor(c compressor) Option {
+	return func(f *FS) {
+		for i := range f.compressors {
+			if f.compressors[i].encoding == c.encoding {
+				f.compressors[i] = c
+				return
+			}
+		}
+		if c.encoding == "br" {
+			f.compressors = append([]compressor{c}, f.compressors...)
+			return
+		}
+		f.compressors = append(f.compressors, c)
+	}
+}
+
+// precompress stores the compressed variants of the given file.
+func (f *FS) precompress(name string) error {
+	if len(f.compressors) == 0 || !compressible(name) {
+		return nil
+	}
+	b, err := fs.ReadFile(f.fs, name)
+	if err != nil {
+		return err
+	}
+	variants := make(map[string][]byte, len(f.compressors))
+	for _, c := range f.compressors {
+		var buf bytes.Buffer
+		w, err := c.compress(&buf)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		if err != nil {
+			return err
+		}
+		err = w.Close()
+		if err != nil {
+			return err
+		}
+		variants[c.encoding] = buf.Bytes()
+	}
+	f.mu.Lock()
+	f.variants[name] = variants
+	f.mu.Unlock()
+	return nil
+}
+
+// variant returns the preferred precompressed variant of the given
+// file acceptable per the Accept-Encoding header.
+func (f *FS) variant(name, acceptEncoding string) (string, []byte, bool) {
+	f.mu.RLock()
+	variants, ok := f.variants[name]
+	f.mu.RUnlock()
+	if !ok {
+		return "", nil, false
+	}
+	for _, c := range f.compressors {
+		b, ok := variants[c.encoding]
+		if ok && acceptsEncoding(acceptEncoding, c.encoding) {
+			return c.encoding, b, true
+		}
+	}
+	return "", nil, false
+}
+
+// compressible reports whether the named file is a text asset.
+func compressible(name string) bool {
+	t, _, _ := mime.ParseMediaType(mime.TypeByExtension(path.Ext(name)))
+	switch {
+	case strings.HasPrefix(t, "text/"), strings.HasSuffix(t, "+xml"), strings.HasSuffix(t, "+json"):
+		return true
+	case t == "application/javascript", t == "application/json", t == "application/xml", t == "application/wasm":
+		return true
+	}
+	return false
+}
+
+// acceptsEncoding reports whether the Accept-Encoding
+// header value accepts the given content encoding.
+func acceptsEncoding(header, encoding string) bool {
+	for _, part := range strings.Split(header, ",") {
+		token := strings.TrimSpace(part)
+		q := ""
+		i := strings.IndexByte(token, ';')
+		if i >= 0 {
+			token, q = strings.TrimSpace(token[:i]), strings.TrimSpace(token[i+1:])
+		}
+		if !strings.EqualFold(token, encoding) && token != "*" {
+			continue
+		}
+		if strings.HasPrefix(q, "q=") {
+			v, err := strconv.ParseFloat(q[2:], 64)
+			if err != nil || v == 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
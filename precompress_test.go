@@ -0,0 +1,88 @@
+package hashfs
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestPrecompress(t *testing.T) {
+	m := fstest.MapFS{
+		"app.js": &fstest.MapFile{Data: []byte("console.log('hello');")},
+		"a.png":  &fstest.MapFile{Data: []byte("a")},
+	}
+	h := New(m, WithPrecompress(gzip.BestCompression))
+	err := h.WarmCache()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := h.variants["a.png"]; ok {
+		t.Errorf("binary assets should not be precompressed")
+	}
+	r := httptest.NewRequest(http.MethodGet, "/"+h.Name("app.js"), nil)
+	r.Header.Set("Accept-Encoding", "gzip, deflate")
+	w := httptest.NewRecorder()
+	h.FileServer().ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status\nhave %d\nwant %d", w.Code, http.StatusOK)
+	}
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Content-Encoding\nhave '%s'\nwant 'gzip'", w.Header().Get("Content-Encoding"))
+	}
+	if w.Header().Get("Vary") != "Accept-Encoding" {
+		t.Errorf("Vary\nhave '%s'\nwant 'Accept-Encoding'", w.Header().Get("Vary"))
+	}
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(b) != string(m["app.js"].Data) {
+		t.Errorf("body\nhave %q\nwant %q", b, m["app.js"].Data)
+	}
+	r = httptest.NewRequest(http.MethodGet, "/"+h.Name("app.js"), nil)
+	w = httptest.NewRecorder()
+	h.FileServer().ServeHTTP(w, r)
+	if w.Header().Get("Content-Encoding") != "" {
+		t.Errorf("Content-Encoding should not be set without Accept-Encoding")
+	}
+	if w.Body.String() != string(m["app.js"].Data) {
+		t.Errorf("body\nhave %q\nwant %q", w.Body.String(), m["app.js"].Data)
+	}
+}
+
+func TestPrecompressInvalid(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("WithPrecompress(10) should panic")
+		}
+	}()
+	WithPrecompress(10)
+}
+
+func TestAcceptsEncoding(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{"", false},
+		{"gzip", true},
+		{"deflate, gzip;q=0.5", true},
+		{"GZIP", true},
+		{"gzip;q=0", false},
+		{"*", true},
+		{"br", false},
+	}
+	for _, tt := range tests {
+		have := acceptsEncoding(tt.header, "gzip")
+		if have != tt.want {
+			t.Errorf("acceptsEncoding(%q)\nhave %t\nwant %t", tt.header, have, tt.want)
+		}
+	}
+}
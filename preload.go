@@ -0,0 +1,95 @@
+package hashfs
+
+import (
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+)
+
+// WithPreload sets the critical assets that FileServer links in the
+// Link header of HTML responses with rel=preload, such as for early
+// hints. The hashed names are resolved once on first use. Assets
+// that could not be hashed are omitted.
+func WithPreload(names ...string) Option {
+	return func(f *FS) {
+		f.preload = &preload{names: append([]string(nil), names...)}
+	}
+}
+
+// preload is the set of critical assets.
+type preload struct {
+	names []string
+	once  sync.Once
+	links []string
+}
+
+// preloadLinks returns the Link header values of the critical assets.
+func (f *FS) preloadLinks() []string {
+	f.preload.once.Do(func() {
+		for _, name := range f.preload.names {
+			hashed := f.nameOrEmpty(name)
+			if hashed == "" {
+				continue
+			}
+			if !strings.HasPrefix(hashed, "/") && !strings.Contains(hashed, "://") {
+				hashed = "/" + hashed
+			}
+			link := "<" + hashed + ">; rel=preload"
+			as := preloadAs(name)
+			if as != "" {
+				link += "; as=" + as
+			}
+			if as == "font" {
+				link += "; crossorigin"
+			}
+			f.preload.links = append(f.preload.links, link)
+		}
+	})
+	return f.preload.links
+}
+
+// preloadAs returns the preload destination of the given file.
+func preloadAs(name string) string {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".js", ".mjs":
+		return "script"
+	case ".css":
+		return "style"
+	case ".woff2", ".woff", ".ttf", ".otf":
+		return "font"
+	case ".png", ".jpg", ".jpeg", ".gif", ".webp", ".avif", ".svg":
+		return "image"
+	}
+	return ""
+}
+
+// preloadWriter is a http.ResponseWriter that
+// adds Link headers to successful HTML responses.
+type preloadWriter struct {
+	http.ResponseWriter
+	links []string
+	wrote bool
+}
+
+// WriteHeader implements the http.ResponseWriter interface.
+func (w *preloadWriter) WriteHeader(code int) {
+	if !w.wrote {
+		w.wrote = true
+		ctype := w.Header().Get("Content-Type")
+		if code == http.StatusOK && strings.HasPrefix(ctype, "text/html") {
+			for _, link := range w.links {
+				w.Header().Add("Link", link)
+			}
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write implements the http.ResponseWriter interface.
+func (w *preloadWriter) Write(b []byte) (int, error) {
+	if !w.wrote {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
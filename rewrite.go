@@ -0,0 +1,112 @@
+package hashfs
+
+import (
+	"io/fs"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// references matches asset references in HTML and CSS. The
+// second submatch of each expression is the referenced path.
+var references = []*regexp.Regexp{
+	regexp.MustCompile(`url\(\s*(['"]?)([^'"()\s]+)['"]?\s*\)`),
+	regexp.MustCompile(`(?i)\b(?:src|href)\s*=\s*(["'])([^"']+)["']`),
+	regexp.MustCompile(`(?i)\b(?:src|href)\s*=\s*()([^\s"'>]+)`),
+}
+
+// RewriteReferences reads the given HTML or CSS file and replaces the
+// paths referenced by url(...), src= and href= with their hashed file
+// names. Relative paths are resolved against the directory of the file
+// and paths with a leading slash against the root. References that are
+// external or could not be hashed are left unchanged.
+func (f *FS) RewriteReferences(name string) ([]byte, error) {
+	b, err := fs.ReadFile(f.fs, name)
+	if err != nil {
+		return nil, err
+	}
+	dir := path.Dir(name)
+	for _, re := range references {
+		b = replaceSubmatch(re, b, 2, func(ref string) string {
+			return f.rewrite(dir, ref)
+		})
+	}
+	return b, nil
+}
+
+// sourceMappingURL matches source map comments in JavaScript. The
+// second submatch is the referenced path.
+var sourceMappingURL = regexp.MustCompile(`(?m)^[ \t]*//([#@])[ \t]*sourceMappingURL=(\S+)[ \t]*$`)
+
+// RewriteSourceMapURL reads the given JavaScript file and replaces the
+// path referenced by its trailing sourceMappingURL comment, in either the
+// "//#" or legacy "//@" form, with the hashed file name of the source map.
+// The file is returned unchanged if it has no such comment or the source
+// map could not be hashed.
+func (f *FS) RewriteSourceMapURL(jsName string) ([]byte, error) {
+	b, err := fs.ReadFile(f.fs, jsName)
+	if err != nil {
+		return nil, err
+	}
+	m := sourceMappingURL.FindAllSubmatchIndex(b, -1)
+	if len(m) == 0 {
+		return b, nil
+	}
+	start, end := m[len(m)-1][4], m[len(m)-1][5]
+	ref := f.rewrite(path.Dir(jsName), string(b[start:end]))
+	out := append([]byte(nil), b[:start]...)
+	out = append(out, ref...)
+	return append(out, b[end:]...), nil
+}
+
+// rewrite returns the hashed reference for ref relative to dir.
+func (f *FS) rewrite(dir, ref string) string {
+	if strings.Contains(ref, ":") || strings.HasPrefix(ref, "//") || strings.HasPrefix(ref, "#") {
+		// External or in-page references.
+		return ref
+	}
+	ref, suffix := splitQuery(ref)
+	if strings.HasPrefix(ref, "/") {
+		hashed := f.hashedName(path.Clean(ref[1:]))
+		if hashed == "" {
+			return ref + suffix
+		}
+		return "/" + hashed + suffix
+	}
+	hashed := f.hashedName(path.Join(dir, ref))
+	if hashed == "" {
+		return ref + suffix
+	}
+	return relative(dir, hashed) + suffix
+}
+
+// relative returns the path of name relative to dir.
+func relative(dir, name string) string {
+	if dir == "." {
+		return name
+	}
+	up := ""
+	for {
+		if strings.HasPrefix(name, dir+"/") {
+			return up + name[len(dir)+1:]
+		}
+		up += "../"
+		dir = path.Dir(dir)
+		if dir == "." {
+			return up + name
+		}
+	}
+}
+
+// replaceSubmatch replaces the n-th submatch of every match of re in b.
+func replaceSubmatch(re *regexp.Regexp, b []byte, n int, fn func(string) string) []byte {
+	var out []byte
+	last := 0
+	for _, m := range re.FindAllSubmatchIndex(b, -1) {
+		start, end := m[2*n], m[2*n+1]
+		out = append(out, b[last:start]...)
+		out = append(out, fn(string(b[start:end]))...)
+		last = end
+	}
+	return append(out, b[last:]...)
+}
@@ -0,0 +1,101 @@
+package hashfs
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestRewriteReferences(t *testing.T) {
+	m := fstest.MapFS{
+		"index.html": &fstest.MapFile{Data: []byte(`<link href="css/app.css" rel="stylesheet">` +
+			`<script src='/js/app.js'></script>` +
+			`<img src=img/a.png>` +
+			`<a href="https://example.com/a.png">` +
+			`<a href="missing.html#top">`)},
+		"css/app.css": &fstest.MapFile{Data: []byte(`body{background:url(../img/a.png)}` +
+			`.b{background:url( "b.png?v=1" )}` +
+			`.c{background:url(data:image/png;base64,AAAA)}`)},
+		"css/b.png": &fstest.MapFile{Data: []byte("b")},
+		"img/a.png": &fstest.MapFile{Data: []byte("a")},
+		"js/app.js": &fstest.MapFile{Data: []byte("a")},
+	}
+	h := New(m, WithHashLength(8))
+	tests := []struct {
+		name string
+		want string
+	}{
+		{
+			"index.html",
+			`<link href="css/app.` + h.Hash("css/app.css") + `.css" rel="stylesheet">` +
+				`<script src='/js/app.ca978112.js'></script>` +
+				`<img src=img/a.ca978112.png>` +
+				`<a href="https://example.com/a.png">` +
+				`<a href="missing.html#top">`,
+		},
+		{
+			"css/app.css",
+			`body{background:url(../img/a.ca978112.png)}` +
+				`.b{background:url( "b.3e23e816.png?v=1" )}` +
+				`.c{background:url(data:image/png;base64,AAAA)}`,
+		},
+	}
+	for _, tt := range tests {
+		b, err := h.RewriteReferences(tt.name)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			continue
+		}
+		if string(b) != tt.want {
+			t.Errorf("RewriteReferences(%q)\nhave '%s'\nwant '%s'", tt.name, b, tt.want)
+		}
+	}
+	_, err := h.RewriteReferences("not-found")
+	if err == nil {
+		t.Errorf("RewriteReferences should error")
+	}
+}
+
+func TestRewriteReferencesPrefixDir(t *testing.T) {
+	m := fstest.MapFS{
+		"css/app.css": &fstest.MapFile{Data: []byte(`url(../img/a.png)`)},
+		"img/a.png":   &fstest.MapFile{Data: []byte("a")},
+	}
+	h := New(m, WithHashLength(8), WithFormat(PrefixDir))
+	b, err := h.RewriteReferences("css/app.css")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `url(../ca978112/img/a.png)`
+	if string(b) != want {
+		t.Errorf("RewriteReferences\nhave '%s'\nwant '%s'", b, want)
+	}
+}
+
+func TestRewriteSourceMapURL(t *testing.T) {
+	m := fstest.MapFS{
+		"js/app.js":     &fstest.MapFile{Data: []byte("a()\n//# sourceMappingURL=app.js.map\n")},
+		"js/legacy.js":  &fstest.MapFile{Data: []byte("a()\n//@ sourceMappingURL=/js/app.js.map")},
+		"js/missing.js": &fstest.MapFile{Data: []byte("a()\n//# sourceMappingURL=missing.js.map\n")},
+		"js/none.js":    &fstest.MapFile{Data: []byte("a()\n")},
+		"js/app.js.map": &fstest.MapFile{Data: []byte("a")},
+	}
+	h := New(m, WithHashLength(8))
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"js/app.js", "a()\n//# sourceMappingURL=app.js.ca978112.map\n"},
+		{"js/legacy.js", "a()\n//@ sourceMappingURL=/js/app.js.ca978112.map"},
+		{"js/missing.js", "a()\n//# sourceMappingURL=missing.js.map\n"},
+		{"js/none.js", "a()\n"},
+	}
+	for _, tt := range tests {
+		b, err := h.RewriteSourceMapURL(tt.name)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if string(b) != tt.want {
+			t.Errorf("RewriteSourceMapURL(%q)\nhave %q\nwant %q", tt.name, b, tt.want)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package hashfs
+
+import (
+	"io/fs"
+	"strconv"
+	"strings"
+)
+
+// WithSizeInName appends the file size in bytes to the digest of hashed
+// file names in the Suffix format, such as "app.<hash>.12345.js". The
+// size is informational and ignored when resolving hashed file names, as
+// the digest is authoritative. Files without a known size, such as those
+// hashed with HashBytes, have no size segment.
+func WithSizeInName(enabled bool) Option {
+	return func(f *FS) {
+		f.sizeInName = enabled
+	}
+}
+
+// size returns the size of the given file.
+func (f *FS) size(name string) (int64, bool) {
+	info, err := fs.Stat(f.fs, name)
+	if err != nil {
+		return 0, false
+	}
+	return info.Size(), true
+}
+
+// sizeSuffix returns the size segment of the given file, if enabled.
+func (f *FS) sizeSuffix(name string) string {
+	if !f.sizeInName {
+		return ""
+	}
+	size, ok := f.size(name)
+	if !ok {
+		return ""
+	}
+	return f.sep + strconv.FormatInt(size, 10)
+}
+
+// trimSize removes the size segment from the given hashed file name,
+// reporting whether the name has one.
+// The segment is the one after the digest, preceding the extension if
+// the file has one.
+func (f *FS) trimSize(file string) (string, bool) {
+	ext := f.ext(file)
+	stem := file[:len(file)-len(ext)]
+	i := strings.LastIndex(stem, f.sep)
+	if i >= 0 && isDigits(stem[i+len(f.sep):]) && strings.Contains(stem[:i], f.sep) {
+		return stem[:i] + ext, true
+	}
+	// Maybe the file has no extension.
+	i = strings.LastIndex(file, f.sep)
+	if i > 0 && isDigits(file[i+len(f.sep):]) {
+		return file[:i], true
+	}
+	return "", false
+}
+
+// isDigits reports whether s is a non-empty decimal number.
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
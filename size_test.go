@@ -0,0 +1,42 @@
+package hashfs
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestWithSizeInName(t *testing.T) {
+	m := fstest.MapFS{
+		"app.js": &fstest.MapFile{Data: []byte("a")},
+		"noext":  &fstest.MapFile{Data: []byte("b")},
+		"v1.2":   &fstest.MapFile{Data: []byte("c")},
+	}
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"app.js", "app.ca978112.1.js"},
+		{"noext", "noext.3e23e816.1"},
+		{"v1.2", "v1.2e7d2c03.1.2"},
+	}
+	for _, tt := range tests {
+		have := New(m, WithHashLength(8), WithSizeInName(true)).Name(tt.name)
+		if have != tt.want {
+			t.Errorf("Name(%q)\nhave '%s'\nwant '%s'", tt.name, have, tt.want)
+			continue
+		}
+		base, ok := New(m, WithHashLength(8), WithSizeInName(true)).Base(have)
+		if !ok || base != tt.name {
+			t.Errorf("Base(%q)\nhave '%s', %t\nwant '%s', true", have, base, ok, tt.name)
+		}
+	}
+	h := New(m, WithHashLength(8), WithSizeInName(true))
+	h.HashBytes("bundle.css", []byte("a"))
+	if h.Name("bundle.css") != "bundle.ca978112.css" {
+		t.Errorf("Name\nhave '%s'\nwant '%s'", h.Name("bundle.css"), "bundle.ca978112.css")
+	}
+	_, ok := h.Base("bundle.ca978112.css")
+	if !ok {
+		t.Errorf("Base should resolve names without a size segment")
+	}
+}
@@ -0,0 +1,44 @@
+//go:build go1.21
+// +build go1.21
+
+package hashfs
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// WithLogger sets the logger of the FS. Computed digests are logged
+// at debug level and hash mismatches rejected by Open at warn level.
+// Logging is off by default or if logger is nil. Requires Go 1.21.
+func WithLogger(logger *slog.Logger) Option {
+	return func(f *FS) {
+		if logger == nil {
+			f.log = nil
+			return
+		}
+		f.log = slogLogger{logger}
+	}
+}
+
+// slogLogger is a logger backed by a slog.Logger.
+type slogLogger struct {
+	*slog.Logger
+}
+
+// hashComputed implements the logger interface.
+func (l slogLogger) hashComputed(name string, size int64, dur time.Duration) {
+	l.LogAttrs(context.Background(), slog.LevelDebug, "hashfs: digest computed",
+		slog.String("name", name),
+		slog.Int64("size", size),
+		slog.Duration("duration", dur),
+	)
+}
+
+// hashMismatch implements the logger interface.
+func (l slogLogger) hashMismatch(name string) {
+	l.LogAttrs(context.Background(), slog.LevelWarn, "hashfs: hash mismatch",
+		slog.String("name", name),
+	)
+}
@@ -0,0 +1,41 @@
+//go:build go1.21
+// +build go1.21
+
+package hashfs
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestWithLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	m := fstest.MapFS{"a.txt": &fstest.MapFile{Data: []byte("a")}}
+	h := New(m, WithLogger(logger), WithHashLength(8))
+	_, err := h.Open("a.3e23e816.txt")
+	if err == nil {
+		t.Fatalf("Open should error on hash mismatch")
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	want := []string{
+		`level=DEBUG msg="hashfs: digest computed" name=a.txt size=1 duration=`,
+		`level=WARN msg="hashfs: hash mismatch" name=a.3e23e816.txt`,
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("log\nhave %q\nwant %q", lines, want)
+	}
+	for i, line := range lines {
+		if !strings.Contains(line, want[i]) {
+			t.Errorf("log line %d\nhave %q\nwant %q", i, line, want[i])
+		}
+	}
+	buf.Reset()
+	New(m, WithLogger(nil)).Name("a.txt")
+	if buf.Len() != 0 {
+		t.Errorf("nil logger should not log")
+	}
+}
@@ -0,0 +1,45 @@
+package hashfs
+
+import (
+	"sort"
+	"time"
+)
+
+// FileStat describes the hashing of a file.
+type FileStat struct {
+	Name     string        // original file name
+	Size     int64         // bytes hashed
+	Digest   string        // encoded digest
+	Duration time.Duration // time spent hashing
+}
+
+// Stats returns the stats of the files hashed so far, sorted by name.
+// Files with digests loaded from a manifest are not included.
+func (f *FS) Stats() []FileStat {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	stats := make([]FileStat, 0, len(f.stats))
+	for _, stat := range f.stats {
+		stats = append(stats, stat)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Name < stats[j].Name
+	})
+	return stats
+}
+
+// setStat performs a synchronized write on the stats.
+func (f *FS) setStat(stat FileStat) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.stats[stat.Name] = stat
+}
+
+// countWriter is an io.Writer that counts the bytes written.
+type countWriter int64
+
+// Write implements the io.Writer interface.
+func (w *countWriter) Write(p []byte) (int, error) {
+	*w += countWriter(len(p))
+	return len(p), nil
+}
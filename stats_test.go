@@ -0,0 +1,45 @@
+package hashfs
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestStats(t *testing.T) {
+	m := fstest.MapFS{
+		"b.txt":     &fstest.MapFile{Data: []byte("b")},
+		"dir/a.txt": &fstest.MapFile{Data: []byte("aaa")},
+	}
+	h := New(m)
+	err := h.WarmCache()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	stats := h.Stats()
+	if len(stats) != 2 {
+		t.Fatalf("len\nhave %d\nwant %d", len(stats), 2)
+	}
+	tests := []struct {
+		name string
+		size int64
+	}{
+		{"b.txt", 1},
+		{"dir/a.txt", 3},
+	}
+	for i, tt := range tests {
+		stat := stats[i]
+		if stat.Name != tt.name {
+			t.Errorf("Name\nhave '%s'\nwant '%s'", stat.Name, tt.name)
+		}
+		if stat.Size != tt.size {
+			t.Errorf("%s Size\nhave %d\nwant %d", tt.name, stat.Size, tt.size)
+		}
+		if stat.Digest != h.Hash(tt.name) {
+			t.Errorf("%s Digest\nhave '%s'\nwant '%s'", tt.name, stat.Digest, h.Hash(tt.name))
+		}
+	}
+	h.Invalidate("b.txt")
+	if len(h.Stats()) != 1 {
+		t.Errorf("Invalidate should drop the stat")
+	}
+}
@@ -0,0 +1,14 @@
+package hashfs
+
+import (
+	"html/template"
+)
+
+// FuncMap returns a template.FuncMap with an "asset" function that
+// returns the hashed file name for the given file. The function
+// returns the original name unchanged if hashing fails.
+func (f *FS) FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"asset": f.NameOrDefault,
+	}
+}
@@ -0,0 +1,30 @@
+package hashfs
+
+import (
+	"html/template"
+	"strings"
+	"testing"
+)
+
+func TestFuncMap(t *testing.T) {
+	h := New(testdata)
+	tmpl := template.Must(template.New("").Funcs(h.FuncMap()).Parse(`<script src="{{ asset . }}"></script>`))
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"testdata/base.ext", `<script src="testdata/base.d476fb7be1b02ea9f66c797a0c11b11ff5db1bd702ff6c4720e455a301a501f1.ext"></script>`},
+		{"not-found.js", `<script src="not-found.js"></script>`},
+	}
+	for _, tt := range tests {
+		var b strings.Builder
+		err := tmpl.Execute(&b, tt.name)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			continue
+		}
+		if b.String() != tt.want {
+			t.Errorf("asset %q\nhave '%s'\nwant '%s'", tt.name, b.String(), tt.want)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package hashfs
+
+import (
+	"bytes"
+	"context"
+	"hash"
+	"io"
+	"path"
+	"strings"
+)
+
+// WithTextNormalization computes the digests of files with the given
+// extensions, such as ".css" and ".js", after normalizing CRLF line
+// endings to LF, so that checkouts on different platforms produce the
+// same hashed file names. The served content and Integrity values are
+// unaffected. Note that this changes the digests of such files compared
+// to the default, so hashed file names are not interchangeable.
+func WithTextNormalization(exts ...string) Option {
+	return func(f *FS) {
+		f.textExts = make(map[string]bool, len(exts))
+		for _, ext := range exts {
+			if !strings.HasPrefix(ext, ".") {
+				ext = "." + ext
+			}
+			f.textExts[strings.ToLower(ext)] = true
+		}
+	}
+}
+
+// normalizesText reports whether the digest of
+// the given file is computed over normalized text.
+func (f *FS) normalizesText(name string) bool {
+	return f.textExts[strings.ToLower(path.Ext(name))]
+}
+
+// textContent returns content with normalized line
+// endings if the given file is normalized.
+func (f *FS) textContent(name string, content []byte) []byte {
+	if !f.normalizesText(name) {
+		return content
+	}
+	return bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n"))
+}
+
+// textDigest is like digest but normalizes line
+// endings if the given file is normalized.
+func (f *FS) textDigest(ctx context.Context, name string, h hash.Hash) ([]byte, error) {
+	if !f.normalizesText(name) {
+		return f.digest(ctx, name, h)
+	}
+	w := &lfWriter{w: h}
+	err := f.copyFile(ctx, name, w)
+	if err != nil {
+		return nil, err
+	}
+	err = w.flush()
+	if err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// lfWriter is a io.Writer that replaces CRLF line endings
+// with LF. Call flush after the last write.
+type lfWriter struct {
+	w  io.Writer
+	cr bool // pending carriage return
+}
+
+// Write implements the io.Writer interface.
+func (w *lfWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	if n == 0 {
+		return 0, nil
+	}
+	if w.cr && p[0] != '\n' {
+		_, err := w.w.Write([]byte{'\r'})
+		if err != nil {
+			return 0, err
+		}
+	}
+	w.cr = p[n-1] == '\r'
+	if w.cr {
+		p = p[:n-1]
+	}
+	_, err := w.w.Write(bytes.ReplaceAll(p, []byte("\r\n"), []byte("\n")))
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// flush writes a pending carriage return.
+func (w *lfWriter) flush() error {
+	if !w.cr {
+		return nil
+	}
+	w.cr = false
+	_, err := w.w.Write([]byte{'\r'})
+	return err
+}
@@ -0,0 +1,50 @@
+package hashfs
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"testing/iotest"
+)
+
+func TestWithTextNormalization(t *testing.T) {
+	m := fstest.MapFS{
+		"lf.css":   &fstest.MapFile{Data: []byte("a {}\nb {}\n")},
+		"crlf.css": &fstest.MapFile{Data: []byte("a {}\r\nb {}\r\n")},
+		"crlf.png": &fstest.MapFile{Data: []byte("a {}\r\nb {}\r\n")},
+	}
+	h := New(m, WithTextNormalization("CSS"))
+	lf := h.Hash("lf.css")
+	if h.Hash("crlf.css") != lf {
+		t.Errorf("Hash\nhave '%s'\nwant '%s'", h.Hash("crlf.css"), lf)
+	}
+	if h.Hash("crlf.png") == lf {
+		t.Errorf("Hash should not normalize other extensions")
+	}
+	if New(m).Hash("crlf.css") == lf {
+		t.Errorf("Hash should not normalize by default")
+	}
+	f, err := h.Open(h.Name("crlf.css"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, _ := io.ReadAll(f)
+	f.Close()
+	if string(b) != "a {}\r\nb {}\r\n" {
+		t.Errorf("Open should serve the original content, have %q", b)
+	}
+	if h.Integrity("crlf.css") != New(m).Integrity("crlf.css") {
+		t.Errorf("Integrity should be of the original content")
+	}
+	for _, s := range []string{"a\r\nb\r\n", "a\rb\r", "a\r\r\nb"} {
+		want := New(m).HashBytes("a.txt", []byte(strings.ReplaceAll(s, "\r\n", "\n")))
+		have, err := h.HashReader("a.css", iotest.OneByteReader(strings.NewReader(s)))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if have != want {
+			t.Errorf("HashReader(%q)\nhave '%s'\nwant '%s'", s, have, want)
+		}
+	}
+}
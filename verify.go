@@ -0,0 +1,21 @@
+package hashfs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Verify reports whether the digest in the given hashed file name, such
+// as "app.<hash>.js", is the sha256 digest of content. The name must use
+// the default configuration, that is the Suffix format with a "." separator
+// and full length hexadecimal digests.
+func Verify(content []byte, hashedName string) bool {
+	f := &FS{sep: "."}
+	_, want, ok := f.parse(hashedName)
+	if !ok {
+		return false
+	}
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])
+	return hash == want
+}
@@ -0,0 +1,24 @@
+package hashfs
+
+import (
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name string
+		ok   bool
+	}{
+		{"a.ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb.txt", true},
+		{"dir/a.ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb", true},
+		{"a.ca978112.txt", false},
+		{"a.3e23e8160039594a33894f6564e1b1348bbd7a0088d42c4acb73eeaed59c009d.txt", false},
+		{"a.txt", false},
+	}
+	for _, tt := range tests {
+		ok := Verify([]byte("a"), tt.name)
+		if ok != tt.ok {
+			t.Errorf("Verify(%q)\nhave %t\nwant %t", tt.name, ok, tt.ok)
+		}
+	}
+}
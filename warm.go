@@ -0,0 +1,183 @@
+package hashfs
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"runtime"
+	"sync"
+)
+
+// errStop is used to stop a walk early.
+var errStop = errors.New("hashfs: stop")
+
+// WithSkipFunc excludes the files for which skip returns true from
+// WarmCache and WalkHashed. Skipping a directory skips its contents.
+func WithSkipFunc(skip func(path string, d fs.DirEntry) bool) Option {
+	return func(f *FS) {
+		f.skip = skip
+	}
+}
+
+// WarmCache walks the underlying file system and hashes every
+// regular file, returning the first error encountered.
+func (f *FS) WarmCache() error {
+	return f.WarmCacheContext(context.Background())
+}
+
+// WarmCacheContext is like WarmCache but stops once
+// the context is done, returning the context error.
+func (f *FS) WarmCacheContext(ctx context.Context) error {
+	err := f.walk(func(name string, d fs.DirEntry) error {
+		err := ctx.Err()
+		if err != nil {
+			return err
+		}
+		return f.warm(ctx, name)
+	})
+	if err != nil {
+		return err
+	}
+	return f.disambiguate()
+}
+
+// walk walks the underlying file system in the order of fs.WalkDir,
+// calling fn for each regular file not skipped by WithSkipFunc.
+// Symbolic links to regular files are treated as such, so that the
+// digest of the target is recorded under the path of the link.
+func (f *FS) walk(fn func(name string, d fs.DirEntry) error) error {
+	return fs.WalkDir(f.fs, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if f.skip != nil && name != "." && f.skip(name, d) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.Type()&fs.ModeSymlink != 0 {
+			return f.walkSymlink(name, d, fn)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		return fn(name, d)
+	})
+}
+
+// walkSymlink calls fn for the given symbolic link if it resolves to a
+// regular file. Links to directories are not followed to avoid cycles.
+// Broken links are skipped, notifying the observer.
+func (f *FS) walkSymlink(name string, d fs.DirEntry, fn func(name string, d fs.DirEntry) error) error {
+	info, err := fs.Stat(f.fs, name)
+	if err != nil {
+		f.skipped(name, err)
+		return nil
+	}
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+	return fn(name, d)
+}
+
+// skipped notifies the observer that the given file was skipped.
+func (f *FS) skipped(name string, err error) {
+	obs, ok := f.obs.(SkipObserver)
+	if ok {
+		obs.Skipped(name, err)
+	}
+}
+
+// warm hashes and precompresses the given file.
+// Files exceeding the maximum size or not
+// hashable per WithExtensions are skipped.
+func (f *FS) warm(ctx context.Context, name string) error {
+	if !f.hashable(name) {
+		return nil
+	}
+	_, err := f.HashContext(ctx, name)
+	if errors.Is(err, ErrTooLarge) {
+		f.skipped(name, err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return f.precompress(name)
+}
+
+// WarmCacheParallel is like WarmCache but hashes files using the given
+// number of worker goroutines, or runtime.GOMAXPROCS(0) workers if
+// workers <= 0. Remaining work, including files being hashed, is
+// canceled on the first error.
+func (f *FS) WarmCacheParallel(workers int) error {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	var (
+		wg    sync.WaitGroup
+		once  sync.Once
+		first error
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fail := func(err error) {
+		once.Do(func() {
+			first = err
+			cancel()
+		})
+	}
+	names := make(chan string)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range names {
+				select {
+				case <-ctx.Done():
+					continue
+				default:
+				}
+				err := f.warm(ctx, name)
+				if err != nil {
+					fail(err)
+				}
+			}
+		}()
+	}
+	err := f.walk(func(name string, d fs.DirEntry) error {
+		select {
+		case names <- name:
+			return nil
+		case <-ctx.Done():
+			return errStop
+		}
+	})
+	if err != nil && err != errStop {
+		fail(err)
+	}
+	close(names)
+	wg.Wait()
+	if first != nil {
+		return first
+	}
+	return f.disambiguate()
+}
+
+// WalkHashed walks the underlying file system in the order of fs.WalkDir,
+// calling fn with the hashed file name of each regular file, or the plain
+// file name if not hashable per WithExtensions. Errors from hashing or fn
+// stop the walk and are returned.
+func (f *FS) WalkHashed(fn func(hashedPath string, d fs.DirEntry) error) error {
+	return f.walk(func(name string, d fs.DirEntry) error {
+		if !f.hashable(name) {
+			return fn(name, d)
+		}
+		hash, err := f.HashErr(name)
+		if err != nil {
+			return err
+		}
+		return fn(f.format(name, hash), d)
+	})
+}
@@ -0,0 +1,288 @@
+package hashfs
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestWarmCache(t *testing.T) {
+	h := New(testdata)
+	err := h.WarmCache()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests := []string{
+		"testdata/base.ext",
+		"testdata/noext",
+	}
+	for _, tt := range tests {
+		_, ok := h.getHash(tt)
+		if !ok {
+			t.Errorf("WarmCache should hash %q", tt)
+		}
+	}
+	if len(h.hash) != len(tests) {
+		t.Errorf("WarmCache should only hash regular files, have %v", h.hash)
+	}
+}
+
+func TestWarmCacheError(t *testing.T) {
+	h := New(failFS{
+		MapFS: fstest.MapFS{"a.txt": &fstest.MapFile{Data: []byte("a")}},
+		name:  "a.txt",
+	})
+	err := h.WarmCache()
+	if !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("WarmCache should yield fs.ErrPermission, have %v", err)
+	}
+}
+
+// failFS is a fs.FS that fails to open the named file.
+type failFS struct {
+	fstest.MapFS
+	name string
+}
+
+// Open implements the fs.FS interface.
+func (f failFS) Open(name string) (fs.File, error) {
+	if name == f.name {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+	}
+	return f.MapFS.Open(name)
+}
+
+// endlessFS is a failFS whose file named big never reaches EOF.
+type endlessFS struct {
+	failFS
+	big string
+}
+
+// Open implements the fs.FS interface.
+func (f endlessFS) Open(name string) (fs.File, error) {
+	file, err := f.failFS.Open(name)
+	if err != nil || name != f.big {
+		return file, err
+	}
+	return endlessFile{file}, nil
+}
+
+// endlessFile is a fs.File that reads forever.
+type endlessFile struct {
+	fs.File
+}
+
+// Read implements the io.Reader interface.
+func (f endlessFile) Read(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func TestWarmCacheParallelCancel(t *testing.T) {
+	h := New(endlessFS{
+		failFS: failFS{
+			MapFS: fstest.MapFS{
+				"a.txt": &fstest.MapFile{Data: []byte("a")},
+				"b.txt": &fstest.MapFile{Data: []byte("b")},
+			},
+			name: "b.txt",
+		},
+		big: "a.txt",
+	})
+	done := make(chan error, 1)
+	go func() {
+		done <- h.WarmCacheParallel(2)
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, fs.ErrPermission) {
+			t.Errorf("WarmCacheParallel should yield fs.ErrPermission, have %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("WarmCacheParallel should cancel files being hashed on error")
+	}
+}
+
+func TestWarmCacheContext(t *testing.T) {
+	h := New(testdata)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := h.WarmCacheContext(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("WarmCacheContext should yield context.Canceled, have %v", err)
+	}
+	if len(h.hash) != 0 {
+		t.Errorf("WarmCacheContext should not hash after cancellation")
+	}
+}
+
+func TestWarmCacheParallel(t *testing.T) {
+	m := make(fstest.MapFS)
+	for _, name := range []string{"a.txt", "b.txt", "dir/c.txt", "dir/d.txt", "dir/sub/e.txt"} {
+		m[name] = &fstest.MapFile{Data: []byte(name)}
+	}
+	tests := []int{-1, 0, 1, 2, 8}
+	for _, tt := range tests {
+		h := New(m)
+		err := h.WarmCacheParallel(tt)
+		if err != nil {
+			t.Errorf("WarmCacheParallel(%d) unexpected error: %v", tt, err)
+			continue
+		}
+		if len(h.hash) != 5 || len(h.base) != 5 {
+			t.Errorf("WarmCacheParallel(%d) should hash every file, have %v", tt, h.hash)
+		}
+	}
+}
+
+func TestWarmCacheParallelError(t *testing.T) {
+	h := New(failFS{
+		MapFS: fstest.MapFS{
+			"a.txt": &fstest.MapFile{Data: []byte("a")},
+			"b.txt": &fstest.MapFile{Data: []byte("b")},
+		},
+		name: "b.txt",
+	})
+	err := h.WarmCacheParallel(2)
+	if !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("WarmCacheParallel should yield fs.ErrPermission, have %v", err)
+	}
+}
+
+func TestWalkHashed(t *testing.T) {
+	m := fstest.MapFS{
+		"a.txt":     &fstest.MapFile{Data: []byte("a")},
+		"dir/b.txt": &fstest.MapFile{Data: []byte("b")},
+	}
+	h := New(m, WithHashLength(8))
+	var names []string
+	err := h.WalkHashed(func(name string, d fs.DirEntry) error {
+		names = append(names, name)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"a.ca978112.txt", "dir/b.3e23e816.txt"}
+	if len(names) != len(want) {
+		t.Fatalf("WalkHashed\nhave %v\nwant %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Errorf("WalkHashed[%d]\nhave '%s'\nwant '%s'", i, names[i], want[i])
+		}
+	}
+	errTest := errors.New("test")
+	calls := 0
+	err = h.WalkHashed(func(name string, d fs.DirEntry) error {
+		calls++
+		return errTest
+	})
+	if err != errTest || calls != 1 {
+		t.Errorf("WalkHashed should stop on callback error, have %v after %d calls", err, calls)
+	}
+}
+
+func TestWithSkipFunc(t *testing.T) {
+	m := fstest.MapFS{
+		"a.js":                    &fstest.MapFile{Data: []byte("a")},
+		"a.js.map":                &fstest.MapFile{Data: []byte("b")},
+		"node_modules/dep/dep.js": &fstest.MapFile{Data: []byte("c")},
+	}
+	skip := func(name string, d fs.DirEntry) bool {
+		return d.Name() == "node_modules" || path.Ext(name) == ".map"
+	}
+	h := New(m, WithSkipFunc(skip))
+	err := h.WarmCache()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(h.hash) != 1 {
+		t.Errorf("WarmCache should skip files, have %v", h.hash)
+	}
+	err = h.WarmCacheParallel(2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(h.hash) != 1 {
+		t.Errorf("WarmCacheParallel should skip files, have %v", h.hash)
+	}
+	var names []string
+	err = h.WalkHashed(func(name string, d fs.DirEntry) error {
+		names = append(names, name)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(names) != 1 || names[0] != "a.ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb.js" {
+		t.Errorf("WalkHashed should skip files, have %v", names)
+	}
+}
+
+// skipObserver records skipped files.
+type skipObserver struct {
+	testObserver
+	skipped []string
+}
+
+// Skipped implements the SkipObserver interface.
+func (o *skipObserver) Skipped(name string, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.skipped = append(o.skipped, name)
+}
+
+func TestWithMaxSize(t *testing.T) {
+	m := fstest.MapFS{
+		"a.txt":   &fstest.MapFile{Data: []byte("a")},
+		"big.txt": &fstest.MapFile{Data: []byte("bigger")},
+	}
+	obs := &skipObserver{}
+	h := New(m, WithMaxSize(4), WithObserver(obs))
+	_, err := h.HashErr("big.txt")
+	if !errors.Is(err, ErrTooLarge) {
+		t.Errorf("HashErr should yield ErrTooLarge, have %v", err)
+	}
+	err = h.WarmCache()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(h.hash) != 1 {
+		t.Errorf("WarmCache should skip large files, have %v", h.hash)
+	}
+	if len(obs.skipped) != 1 || obs.skipped[0] != "big.txt" {
+		t.Errorf("skipped\nhave %v\nwant %v", obs.skipped, []string{"big.txt"})
+	}
+}
+
+func TestWarmCacheSymlink(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0644)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for link, target := range map[string]string{"link.txt": "a.txt", "broken.txt": "missing.txt", "dir": "."} {
+		err = os.Symlink(target, filepath.Join(dir, link))
+		if err != nil {
+			t.Skipf("symlinks not supported: %v", err)
+		}
+	}
+	obs := &skipObserver{}
+	h := NewFromDir(dir, WithObserver(obs))
+	err = h.WarmCache()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if h.Hash("link.txt") != h.Hash("a.txt") || len(h.hash) != 2 {
+		t.Errorf("WarmCache should hash links to files, have %v", h.hash)
+	}
+	if len(obs.skipped) != 1 || obs.skipped[0] != "broken.txt" {
+		t.Errorf("skipped\nhave %v\nwant %v", obs.skipped, []string{"broken.txt"})
+	}
+}
@@ -0,0 +1,65 @@
+//go:build fsnotify
+// +build fsnotify
+
+package hashfs
+
+import (
+	"context"
+	"io/fs"
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Watch watches the os directory root of the FS, such as the directory
+// given to NewFromDir, and invalidates the cached digests of files as
+// they are written, renamed or removed, so that the next lookup
+// recomputes them. Only works for os backed file systems. Blocks until
+// the context is done, returning the context error, or the watcher
+// fails. Requires the fsnotify build tag.
+func (f *FS) Watch(ctx context.Context, root string) error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	err = watchDir(w, root)
+	if err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-w.Errors:
+			return err
+		case event := <-w.Events:
+			if event.Op&fsnotify.Create != 0 {
+				// New directories are watched along with their subdirectories.
+				_ = watchDir(w, event.Name)
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Rename|fsnotify.Remove|fsnotify.Create) == 0 {
+				continue
+			}
+			name, err := filepath.Rel(root, event.Name)
+			if err != nil {
+				continue
+			}
+			f.Invalidate(filepath.ToSlash(name))
+		}
+	}
+}
+
+// watchDir adds the given directory and its subdirectories to w.
+// Files are ignored.
+func watchDir(w *fsnotify.Watcher, root string) error {
+	return filepath.WalkDir(root, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		return w.Add(name)
+	})
+}
@@ -0,0 +1,52 @@
+//go:build fsnotify
+// +build fsnotify
+
+package hashfs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	err := os.Mkdir(filepath.Join(dir, "css"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := filepath.Join(dir, "css", "a.css")
+	err = os.WriteFile(name, []byte("a"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewFromDir(dir, WithHashLength(8))
+	if h.Name("css/a.css") != "css/a.ca978112.css" {
+		t.Fatalf("Name\nhave '%s'\nwant '%s'", h.Name("css/a.css"), "css/a.ca978112.css")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- h.Watch(ctx, dir)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	err = os.WriteFile(name, []byte("b"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "css/a.3e23e816.css"
+	deadline := time.Now().Add(time.Second)
+	for h.Name("css/a.css") != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("Name\nhave '%s'\nwant '%s'", h.Name("css/a.css"), want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	err = <-done
+	if err != context.Canceled {
+		t.Errorf("Watch\nhave %v\nwant %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,33 @@
+package hashfs
+
+import (
+	"path/filepath"
+)
+
+// WithXattrDigest reads the encoded digests of files from the named
+// extended attribute, such as "user.sha256", instead of hashing their
+// contents, falling back to hashing if the attribute is absent. Digests
+// read from attributes are trusted and not verified against the file
+// contents. Only applies to a FS created with NewFromDir on platforms
+// with extended attribute support.
+func WithXattrDigest(attrName string) Option {
+	return func(f *FS) {
+		f.xattr = attrName
+	}
+}
+
+// xattrDigest returns the digest of the given file
+// from the extended attribute, if configured.
+func (f *FS) xattrDigest(name string) (string, bool) {
+	if f.xattr == "" || f.dir == "" {
+		return "", false
+	}
+	hash, ok := getxattr(filepath.Join(f.dir, filepath.FromSlash(name)), f.xattr)
+	if !ok || hash == "" {
+		return "", false
+	}
+	if f.length > 0 && len(hash) > f.length {
+		hash = hash[:f.length]
+	}
+	return hash, true
+}
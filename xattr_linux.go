@@ -0,0 +1,24 @@
+//go:build linux
+// +build linux
+
+package hashfs
+
+import (
+	"syscall"
+)
+
+// getxattr returns the value of the named extended attribute of the file.
+func getxattr(path, attr string) (string, bool) {
+	buf := make([]byte, 256)
+	for {
+		n, err := syscall.Getxattr(path, attr, buf)
+		if err == syscall.ERANGE {
+			buf = make([]byte, 2*len(buf))
+			continue
+		}
+		if err != nil {
+			return "", false
+		}
+		return string(buf[:n]), true
+	}
+}
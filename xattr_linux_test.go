@@ -0,0 +1,72 @@
+//go:build linux
+// +build linux
+
+package hashfs
+
+import (
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestWithXattrDigest(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(dir, "b.txt"), []byte("b"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = syscall.Setxattr(filepath.Join(dir, "a.txt"), "user.sha256", []byte("0123456789abcdef"), 0)
+	if err != nil {
+		t.Skipf("extended attributes not supported: %v", err)
+	}
+	h := NewFromDir(dir, WithXattrDigest("user.sha256"), WithHashLength(8))
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"a.txt", "a.01234567.txt"},
+		{"b.txt", "b.3e23e816.txt"},
+	}
+	for _, tt := range tests {
+		have := h.Name(tt.name)
+		if have != tt.want {
+			t.Errorf("Name(%s)\nhave '%s'\nwant '%s'", tt.name, have, tt.want)
+		}
+	}
+	have, ok := h.Base("a.01234567.txt")
+	if !ok || have != "a.txt" {
+		t.Errorf("Base\nhave '%s'\nwant '%s'", have, "a.txt")
+	}
+}
+
+func TestWithXattrDigestIntegrity(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "a.txt")
+	err := os.WriteFile(name, []byte("a"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = syscall.Setxattr(name, "user.sha256", []byte("0123456789abcdef"), 0)
+	if err != nil {
+		t.Skipf("extended attributes not supported: %v", err)
+	}
+	h := NewFromDir(dir, WithXattrDigest("user.sha256"))
+	want := "sha256-ypeBEsobvcr6wjGzmiPcTaeG7/gUfE5yuYB3ha/uSLs="
+	have := h.Integrity("a.txt")
+	if have != want {
+		t.Errorf("Integrity\nhave '%s'\nwant '%s'", have, want)
+	}
+	sum, err := NewFromDir(dir, WithXattrDigest("user.sha256")).RawHash("a.txt")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if hex.EncodeToString(sum) != "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb" {
+		t.Errorf("RawHash\nhave '%x'", sum)
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+// +build !linux
+
+package hashfs
+
+// getxattr reports false as extended attributes are not supported.
+func getxattr(path, attr string) (string, bool) {
+	return "", false
+}